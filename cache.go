@@ -0,0 +1,186 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+// magicBytes starts cache files whose header holds the time they were
+// written. Files starting with legacyMagicBytes were written before that,
+// and their modification time is used instead.
+var (
+	magicBytes       = [4]byte{0x35, 0x77, 0x69, 0xFC}
+	legacyMagicBytes = [4]byte{0x35, 0x77, 0x69, 0xFB}
+)
+
+// defaultCacheDuration is how long cached responses are used by default.
+const defaultCacheDuration = 48 * time.Hour
+
+// cacheFile is a file caching a single response. The file starts with a
+// header holding magicBytes, the time it was written and a key, followed
+// by the response body. The cache is only used if the key matches, so the
+// key should change whenever the request does.
+//
+// The write time is taken from Clock, so that expiry follows the same
+// clock as the rest of the request.
+type cacheFile struct {
+	Path     string
+	Key      string
+	Duration time.Duration
+	Clock    Clock
+	FileMode os.FileMode
+	DirMode  os.FileMode
+}
+
+// open returns the cached response body, or nil if there is no usable
+// cache. If stale is true, the cache is used even if it has expired, which
+// allows it to serve as a fallback when the service can't be reached.
+func (c *cacheFile) open(stale bool) (io.ReadCloser, error) {
+	if c.Path == "" {
+		return nil, nil
+	}
+	if err := checkFile(c.Path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// File doesn't exist, not a problem
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	h, err := readCacheHeader(f)
+	if err != nil || h == nil || h.key != c.Key {
+		// An unknown format or a changed key resets the cache.
+		_ = f.Close()
+		return nil, err
+	}
+
+	d := c.Duration
+	if d == 0 {
+		d = defaultCacheDuration
+	}
+
+	if !stale && h.written.Add(d).Before(clockOrSystem(c.Clock).Now()) {
+		// Cache is busted, re-request. The file is kept around as a stale
+		// fallback and is replaced once the new response arrives.
+		_ = f.Close()
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+// store replaces the cache with b, which should have been validated
+// already. The cache is replaced atomically, so concurrent readers never
+// see a partial response. Without a Path, store does nothing.
+func (c *cacheFile) store(b []byte) error {
+	if c.Path == "" {
+		return nil
+	}
+
+	// Make sure the directory holding our cache exists.
+	if err := mkdirFor(c.Path, dirMode(c.DirMode)); err != nil {
+		return err
+	}
+
+	f, err := createTemp(c.Path, fileMode(c.FileMode))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	// Write the cache header
+	if err := writeCacheHeader(f, c.Key, clockOrSystem(c.Clock).Now()); err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Rename is atomic, so concurrent stores don't need a lock: the last
+	// one wins with a complete response.
+	return os.Rename(f.Name(), c.Path)
+}
+
+// cacheHeader is the header of a cache file.
+type cacheHeader struct {
+	written time.Time
+	key     string
+}
+
+// readCacheHeader reads the header of the cache file f, leaving f at the
+// start of the response body. It returns nil if the file doesn't start
+// with a known signature.
+func readCacheHeader(f *os.File) (*cacheHeader, error) {
+	// Check the signature of the file
+	var sig [4]byte
+	if err := binary.Read(f, binary.LittleEndian, sig[:]); err != nil {
+		return nil, err
+	}
+
+	h := &cacheHeader{}
+	switch sig {
+	case magicBytes:
+		var nsec int64
+		if err := binary.Read(f, binary.LittleEndian, &nsec); err != nil {
+			return nil, err
+		}
+		h.written = time.Unix(0, nsec)
+	case legacyMagicBytes:
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		h.written = fi.ModTime()
+	default:
+		// Signatures don't match. Reset.
+		return nil, nil
+	}
+
+	var length uint32
+	if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	key := make([]byte, length)
+	if _, err := io.ReadFull(f, key); err != nil {
+		return nil, err
+	}
+
+	h.key = string(key)
+	return h, nil
+}
+
+func writeCacheHeader(f io.Writer, v string, written time.Time) error {
+	// Write our signature first, followed by the time of writing
+	if err := binary.Write(f, binary.LittleEndian, magicBytes); err != nil {
+		return err
+	}
+	if err := binary.Write(f, binary.LittleEndian, written.UnixNano()); err != nil {
+		return err
+	}
+
+	// Write out our current key length
+	length := uint32(len(v))
+	if err := binary.Write(f, binary.LittleEndian, length); err != nil {
+		return err
+	}
+
+	_, err := f.Write([]byte(v))
+	return err
+}
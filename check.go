@@ -4,27 +4,23 @@
 package checkpoint
 
 import (
-	crand "crypto/rand"
-	"encoding/binary"
-	"encoding/json"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	mrand "math/rand"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/hashicorp/go-cleanhttp"
 )
 
-var magicBytes = [4]byte{0x35, 0x77, 0x69, 0xFB}
-
 // CheckParams are the parameters for configuring a check request.
 type CheckParams struct {
 	// Product and version are used to lookup the correct product and
@@ -39,6 +35,15 @@ type CheckParams struct {
 	Arch string
 	OS   string
 
+	// Channel and Edition, if set, are sent to target alerts, such as the
+	// "beta" channel or the "enterprise" edition. Extra holds any other
+	// dimensions, such as "installer" or "fips". They are all sent in the
+	// query string and are part of the cache key. Extra can't use the keys
+	// of the other parameters, which are reserved.
+	Channel string
+	Edition string
+	Extra   map[string]string
+
 	// Signature is some random signature that should be stored and used
 	// as a cookie-like value. This ensures that alerts aren't repeated.
 	// If the signature is changed, repeat alerts may be sent down. The
@@ -49,8 +54,13 @@ type CheckParams struct {
 	// file. If the file doesn't exist, then a random signature will
 	// automatically be generated and stored here. SignatureFile will be
 	// ignored if Signature is given.
-	Signature     string
-	SignatureFile string
+	//
+	// SignatureStore, if given, is used instead of SignatureFile. If
+	// neither is given and CHECKPOINT_SIGNATURE is set, the signature is
+	// read from that environment variable. See SignatureStore.
+	Signature      string
+	SignatureFile  string
+	SignatureStore SignatureStore `json:"-"`
 
 	// CacheFile, if specified, will cache the result of a check. The
 	// duration of the cache is specified by CacheDuration, and defaults
@@ -59,15 +69,110 @@ type CheckParams struct {
 	// results.
 	//
 	// If the CacheFile directory doesn't exist, it will be created with
-	// permissions 0755.
+	// permissions DirMode.
 	CacheFile     string
 	CacheDuration time.Duration
 
+	// FileMode and DirMode are the permissions used when creating the
+	// CacheFile, the SignatureFile and the directories holding them. They
+	// default to DefaultFileMode and DefaultDirMode, so that other users
+	// can't read them. Existing files that are symbolic links or are owned
+	// by another user are refused with an UnsafeFileError.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+
+	// MaxResponseSize is the maximum size of a response, in bytes. It
+	// defaults to DefaultMaxResponseSize. Larger responses are rejected
+	// with a ResponseSizeError, and so are responses whose Content-Type
+	// isn't JSON, with ErrContentType.
+	//
+	// StrictDecoding, if true, rejects responses with unknown fields or
+	// data after the JSON value.
+	MaxResponseSize int64
+	StrictDecoding  bool
+
+	// Decoders decompress responses with content codings other than gzip
+	// and deflate, which are always supported. They are keyed on the
+	// coding, such as "br" or "zstd", and preferred over the built-in
+	// ones. Responses are decoded whatever the transport of the
+	// HTTPClient, and cached decompressed.
+	Decoders map[string]Decoder `json:"-"`
+
+	// PublicKeys, if set, are the ed25519 keys trusted to sign check
+	// responses. Responses, including cached ones, are then rejected with
+	// ErrResponseSignature unless they are signed by one of the keys. Keys
+	// should be pinned in the code of the product.
+	//
+	// The signature covers the exact bytes of the response body and is
+	// sent base64-encoded in the X-Checkpoint-Signature header, or in an
+	// envelope body of the form {"response": {...}, "signature": "..."}.
+	PublicKeys []ed25519.PublicKey `json:"-"`
+
+	// RequireMetadata, if true, rejects responses without metadata. Any
+	// metadata present is always enforced: expired responses are rejected,
+	// and so are responses older than the highest version seen, which is
+	// kept in a file next to the CacheFile. See CheckMetadata.
+	RequireMetadata bool
+
 	// Force, if true, will force the check even if CHECKPOINT_DISABLE
 	// is set. Within HashiCorp products, this is ONLY USED when the user
 	// specifically requests it. This is never automatically done without
 	// the user's consent.
 	Force bool
+
+	// DisableDeduplication, if true, disables sharing of in-flight
+	// requests. By default, concurrent checks in the same process for the
+	// same product, version, OS, arch and CacheFile share a single request
+	// and its result.
+	DisableDeduplication bool
+
+	// Memo, if set, keeps successful responses in memory, in front of the
+	// CacheFile. See Memo.
+	Memo *Memo `json:"-"`
+
+	// Clock and RandSource, if set, replace the system clock and the
+	// global random source. They are used for cache expiry and the
+	// scheduling of CheckInterval, and allow tests to be deterministic.
+	// RandSource is only used from the CheckInterval goroutine.
+	Clock      Clock        `json:"-"`
+	RandSource mrand.Source `json:"-"`
+
+	// Policy decides whether checks are allowed. The environment can
+	// disable checks even if Policy allows them. See PolicyFromEnv.
+	Policy *Policy `json:"-"`
+
+	// Proxy, if set, returns the proxy for a request, such as
+	// http.ProxyURL for a fixed proxy with credentials. Otherwise, the
+	// CHECKPOINT_PROXY environment variable is used, and then the standard
+	// HTTPS_PROXY. CHECKPOINT_PROXY is ignored if HTTPClient has a
+	// transport other than an *http.Transport. Failures of the proxy are
+	// returned as a ProxyError.
+	Proxy func(*http.Request) (*url.URL, error) `json:"-"`
+
+	// TLS configures the TLS connections, such as trusted CAs and pins.
+	// See TLSOptions.
+	TLS *TLSOptions `json:"-"`
+
+	// Tracer, if set, traces the steps of the check. See Tracer.
+	Tracer Tracer `json:"-"`
+
+	// Metrics, if set, receives measurements of the requests made, such
+	// as their status and latency. See Metrics.
+	Metrics Metrics `json:"-"`
+
+	// Logger, if set, receives structured debug and info records about the
+	// decisions made, such as cache hits and misses and failed requests.
+	// If it is nil, the CHECKPOINT_LOG environment variable decides
+	// whether anything is logged.
+	Logger *slog.Logger `json:"-"`
+
+	// UserAgent, if set, replaces the User-Agent header, which otherwise
+	// names this library, the product and the platform.
+	// UserAgentExtra is appended to the default User-Agent, such as
+	// "my-wrapper/1.2".
+	UserAgent      string `json:"-"`
+	UserAgentExtra string `json:"-"`
+
 	// HTTPClient allows injecting a custom HTTP client (for testing).
 	HTTPClient *http.Client `json:"-"`
 }
@@ -82,6 +187,10 @@ type CheckResponse struct {
 	ProjectWebsite      string        `json:"project_website"`
 	Outdated            bool          `json:"outdated"`
 	Alerts              []*CheckAlert `json:"alerts"`
+
+	// Metadata protects the response against rollback and freeze attacks,
+	// if the server sends it.
+	Metadata *CheckMetadata `json:"metadata,omitempty"`
 }
 
 // CheckAlert is a single alert message from a check request.
@@ -97,47 +206,130 @@ type CheckAlert struct {
 }
 
 // Check checks for alerts and new version information.
+//
+// If the signature store keeps track of acknowledged alerts, such as
+// FileSignatureStore, those alerts are left out of the response.
 func Check(p *CheckParams) (*CheckResponse, error) {
-	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" && !p.Force {
+	return CheckContext(context.Background(), p)
+}
+
+// CheckContext is like Check, but the request is made with ctx, which also
+// carries the parent of the spans started by CheckParams.Tracer.
+//
+// Canceling ctx, or reaching its deadline, makes CheckContext return the
+// error of ctx right away. When concurrent checks share a single request,
+// that request keeps going for the other callers, and its response is
+// still cached.
+func CheckContext(ctx context.Context, p *CheckParams) (*CheckResponse, error) {
+	if !policyOrEnv(p.Policy).Allowed(CapabilityCheck, p.Force) {
+		logger(p.Logger).Info("check disabled by policy",
+			logKeyProduct, p.Product, logKeyVersion, p.Version, logKeyCapability, CapabilityCheck)
 		return &CheckResponse{}, nil
 	}
 
+	if p.Arch == "" {
+		p.Arch = runtime.GOARCH
+	}
+	if p.OS == "" {
+		p.OS = runtime.GOOS
+	}
+	if _, err := p.extraQuery(); err != nil {
+		return nil, err
+	}
+
+	resp, err := checkMemo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	acked, err := p.acknowledgedAlerts()
+	if err != nil {
+		return nil, err
+	}
+	resp.filterAlerts(acked)
+
+	return resp, nil
+}
+
+// checkMemo returns the memoized response for the check, or performs the
+// check and memoizes its response.
+func checkMemo(ctx context.Context, p *CheckParams) (*CheckResponse, error) {
+	memoKey := p.memoKey()
+	// Memoized responses may have expired since they were checked.
+	if v, ok := p.Memo.get(memoKey); ok && p.checkMetadata(v.(*CheckResponse)) == nil {
+		logger(p.Logger).Debug("memo hit", logKeyEndpoint, EndpointCheck,
+			logKeyProduct, p.Product, logKeyVersion, p.Version)
+		metricsOrNop(p.Metrics).IncCacheHits(EndpointCheck)
+		return v.(*CheckResponse).clone(), nil
+	}
+
+	var resp *CheckResponse
+	var err error
+	if p.DisableDeduplication {
+		resp, err = check(ctx, p)
+	} else {
+		key := strings.Join([]string{p.Product, p.cacheKey(), p.OS, p.Arch, p.CacheFile,
+			p.validationKey()}, "\x00")
+		resp, err = checkFlights.do(ctx, key, func() (*CheckResponse, error) {
+			// The request is shared, so it must outlive any single caller.
+			return check(context.WithoutCancel(ctx), p)
+		})
+		// Every caller gets its own copy of the shared response.
+		resp = resp.clone()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.Memo.set(memoKey, resp.clone())
+	return resp, nil
+}
+
+// checkFlights de-duplicates concurrent checks.
+var checkFlights flightGroup[*CheckResponse]
+
+func check(ctx context.Context, p *CheckParams) (*CheckResponse, error) {
 	// Set a default timeout of 3 sec for the check request (in milliseconds)
 	timeout := 3000
 	if _, err := strconv.Atoi(os.Getenv("CHECKPOINT_TIMEOUT")); err == nil {
 		timeout, _ = strconv.Atoi(os.Getenv("CHECKPOINT_TIMEOUT"))
 	}
 
+	cache := &cacheFile{
+		Path:     p.CacheFile,
+		Key:      p.cacheKey(),
+		Duration: p.CacheDuration,
+		Clock:    p.Clock,
+		FileMode: p.FileMode,
+		DirMode:  p.DirMode,
+	}
+
+	log := logger(p.Logger).With(logKeyEndpoint, EndpointCheck,
+		logKeyProduct, p.Product, logKeyVersion, p.Version)
+	metrics := metricsOrNop(p.Metrics)
+	span := SpanInfo{Endpoint: EndpointCheck, Product: p.Product, Version: p.Version}
+
 	// If we have a cached result, then use that
-	if r, err := checkCache(p.Version, p.CacheFile, p.CacheDuration); err != nil {
-		return nil, err
-	} else if r != nil {
-		defer func() {
-			_ = r.Close()
-		}()
-		return checkResult(r)
+	if result, err := checkCache(ctx, p, cache, span, log, metrics); err != nil || result != nil {
+		return result, err
 	}
 
 	var u url.URL
 
-	if p.Arch == "" {
-		p.Arch = runtime.GOARCH
-	}
-	if p.OS == "" {
-		p.OS = runtime.GOOS
+	// If we're given a SignatureStore or SignatureFile, then attempt to
+	// read that.
+	span.Name = SpanSignatureLoad
+	_, end := startSpan(ctx, p.Tracer, span)
+	signature, err := resolveSignature(p.Signature, p.SignatureStore, p.fileSignatureStore(), log)
+	end(err)
+	if err != nil {
+		return nil, err
 	}
 
-	// If we're given a SignatureFile, then attempt to read that.
-	signature := p.Signature
-	if p.Signature == "" && p.SignatureFile != "" {
-		var err error
-		signature, err = checkSignature(p.SignatureFile)
-		if err != nil {
-			return nil, err
-		}
+	v, err := p.extraQuery()
+	if err != nil {
+		return nil, err
 	}
-
-	v := u.Query()
 	v.Set("version", p.Version)
 	v.Set("arch", p.Arch)
 	v.Set("os", p.OS)
@@ -148,23 +340,37 @@ func Check(p *CheckParams) (*CheckResponse, error) {
 	u.Path = fmt.Sprintf("/v1/check/%s", p.Product)
 	u.RawQuery = v.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	span.Name = SpanHTTPRequest
+	reqCtx, end := startSpan(ctx, p.Tracer, span)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", u.String(), nil)
 	if err != nil {
+		end(err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "HashiCorp/go-checkpoint")
-
-	client := p.HTTPClient
-	if client == nil {
-		client = cleanhttp.DefaultClient()
+	// Setting Accept-Encoding turns off the transparent gzip of the
+	// transport, so responses are always decoded by readResponse.
+	req.Header.Set("Accept-Encoding", acceptEncoding(p.Decoders))
+	req.Header.Set("User-Agent", userAgent(p.Product, p.Version, p.UserAgent, p.UserAgentExtra))
+
+	// The client is a copy, so that setting the timeout doesn't race with
+	// other users of it.
+	client, err := newHTTPClient(p.HTTPClient, transportOptions{TLS: p.TLS, Proxy: p.Proxy, Log: log})
+	if err != nil {
+		end(err)
+		return nil, err
 	}
 	// We use a short timeout since checking for new versions is not critical
 	// enough to block on if checkpoint is broken/slow.
 	client.Timeout = time.Duration(timeout) * time.Millisecond
 
+	clock := clockOrSystem(p.Clock)
+	start := clock.Now()
 	resp, err := client.Do(req)
+	observeRequest(metrics, EndpointCheck, start, clock.Now(), resp, err)
 	if err != nil {
+		end(err)
+		logRequestError(log, err)
 		return nil, err
 	}
 	defer func() {
@@ -172,209 +378,305 @@ func Check(p *CheckParams) (*CheckResponse, error) {
 	}()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("unknown status: %d", resp.StatusCode)
+		err := fmt.Errorf("unknown status: %d", resp.StatusCode)
+		end(err)
+		log.Info("request failed", logKeyStatus, resp.StatusCode)
+		return nil, err
 	}
+	end(nil)
 
-	var r io.Reader = resp.Body
-	if p.CacheFile != "" {
-		// Make sure the directory holding our cache exists.
-		if err := os.MkdirAll(filepath.Dir(p.CacheFile), 0755); err != nil {
-			return nil, err
+	span.Name = SpanDecode
+	_, end = startSpan(ctx, p.Tracer, span)
+	result, err := p.decodeResponse(resp, cache)
+	end(err)
+	if err != nil {
+		if rejected(err) {
+			log.Info("response rejected", logKeyError, err)
 		}
+		return nil, err
+	}
 
-		// We have to cache the result, so write the response to the
-		// file as we read it.
-		f, err := os.Create(p.CacheFile)
-		if err != nil {
-			return nil, err
-		}
+	return result, nil
+}
 
-		// Write the cache header
-		if err := writeCacheHeader(f, p.Version); err != nil {
-			_ = f.Close()
-			_ = os.Remove(p.CacheFile)
-			return nil, err
+// checkCache returns the response in the CacheFile, or nil if there is
+// none.
+func checkCache(ctx context.Context, p *CheckParams, cache *cacheFile, span SpanInfo, log *slog.Logger, metrics Metrics) (result *CheckResponse, err error) {
+	if p.CacheFile == "" {
+		if p.Memo != nil {
+			metrics.IncCacheMisses(EndpointCheck)
 		}
-
-		defer func() {
-			_ = f.Close()
-		}()
-		r = io.TeeReader(r, f)
+		return nil, nil
 	}
 
-	return checkResult(r)
-}
-
-// CheckInterval is used to check for a response on a given interval duration.
-// The interval is not exact, and checks are randomized to prevent a thundering
-// herd. However, it is expected that on average one check is performed per
-// interval. The returned channel may be closed to stop background checks.
-func CheckInterval(p *CheckParams, interval time.Duration, cb func(*CheckResponse, error)) chan struct{} {
-	doneCh := make(chan struct{})
+	span.Name = SpanCacheLookup
+	_, end := startSpan(ctx, p.Tracer, span)
+	defer func() {
+		end(err)
+	}()
 
-	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" {
-		return doneCh
+	r, err := cache.open(false)
+	if err != nil {
+		log.Debug("cache unreadable", logKeyCacheFile, p.CacheFile, logKeyError, err)
+		return nil, err
 	}
-
-	go func() {
-		for {
-			select {
-			case <-time.After(randomStagger(interval)):
-				resp, err := Check(p)
-				cb(resp, err)
-			case <-doneCh:
-				return
-			}
-		}
+	if r == nil {
+		log.Debug("cache miss", logKeyCacheFile, p.CacheFile)
+		metrics.IncCacheMisses(EndpointCheck)
+		return nil, nil
+	}
+	defer func() {
+		_ = r.Close()
 	}()
 
-	return doneCh
-}
+	b, err := readLimited(r, p.MaxResponseSize)
+	if err == nil {
+		result, err = p.checkResult(b)
+	}
+	if err == nil {
+		err = p.checkMetadata(result)
+	}
+	if err != nil {
+		// The cache was tampered with, has expired or can't be decoded,
+		// such as when it was written without StrictDecoding, so make a
+		// new request.
+		log.Info("cached response rejected", logKeyCacheFile, p.CacheFile, logKeyError, err)
+		metrics.IncCacheMisses(EndpointCheck)
+		return nil, nil
+	}
 
-// randomStagger returns an interval that is between 3/4 and 5/4 of
-// the given interval. The expected value is the interval.
-func randomStagger(interval time.Duration) time.Duration {
-	stagger := time.Duration(mrand.Int63()) % (interval / 2)
-	return 3*(interval/4) + stagger
+	log.Debug("cache hit", logKeyCacheFile, p.CacheFile)
+	metrics.IncCacheHits(EndpointCheck)
+	return result, nil
 }
 
-func checkCache(current string, path string, d time.Duration) (io.ReadCloser, error) {
-	fi, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, not a problem
-			return nil, nil
-		}
-
-		return nil, err
+// memoKey returns the key of the check in a Memo. It covers all of the
+// request parameters.
+func (p *CheckParams) memoKey() string {
+	goarch, goos := p.Arch, p.OS
+	if goarch == "" {
+		goarch = runtime.GOARCH
 	}
-
-	if d == 0 {
-		d = 48 * time.Hour
+	if goos == "" {
+		goos = runtime.GOOS
 	}
 
-	if fi.ModTime().Add(d).Before(time.Now()) {
-		// Cache is busted, delete the old file and re-request. We ignore
-		// errors here because re-creating the file is fine too.
-		_ = os.Remove(path)
-		return nil, nil
+	return memoKey("check", p.Product, p.cacheKey(), goarch, goos,
+		p.Signature, p.SignatureFile, p.CacheFile, p.CacheDuration.String(),
+		p.validationKey())
+}
+
+// validationKey identifies how responses are validated: the public keys
+// they are verified with, the metadata they require and how they are
+// decoded. Checks only share responses, through a shared request or the
+// Memo, if they validate them the same way.
+func (p *CheckParams) validationKey() string {
+	h := sha256.New()
+	for _, key := range p.PublicKeys {
+		h.Write(key)
 	}
+	return fmt.Sprintf("%x\x00%t\x00%t\x00%d",
+		h.Sum(nil), p.RequireMetadata, p.StrictDecoding, p.MaxResponseSize)
+}
 
-	// File looks good so far, open it up so we can inspect the contents.
-	f, err := os.Open(path)
+// decodeResponse reads, validates and decodes the response, and then
+// stores it in the cache. Nothing is cached unless the response is valid.
+func (p *CheckParams) decodeResponse(resp *http.Response, cache *cacheFile) (*CheckResponse, error) {
+	b, err := readResponse(resp, p.MaxResponseSize, p.Decoders)
 	if err != nil {
 		return nil, err
 	}
+	if len(p.PublicKeys) > 0 {
+		if b, err = signedBody(resp.Header, b); err != nil {
+			return nil, err
+		}
+	}
 
-	// Check the signature of the file
-	var sig [4]byte
-	if err := binary.Read(f, binary.LittleEndian, sig[:]); err != nil {
-		_ = f.Close()
+	result, err := p.checkResult(b)
+	if err != nil {
 		return nil, err
 	}
-	if !reflect.DeepEqual(sig, magicBytes) {
-		// Signatures don't match. Reset.
-		_ = f.Close()
-		return nil, nil
+	if err := p.checkMetadata(result); err != nil {
+		return nil, err
 	}
 
-	// Check the version. If it changed, then rewrite
-	var length uint32
-	if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
-		_ = f.Close()
+	if err := cache.store(b); err != nil {
 		return nil, err
 	}
-	data := make([]byte, length)
-	if _, err := io.ReadFull(f, data); err != nil {
-		_ = f.Close()
+	if err := p.storeMetadata(result); err != nil {
 		return nil, err
 	}
-	if string(data) != current {
-		// Version changed, reset
-		_ = f.Close()
-		return nil, nil
-	}
+	return result, nil
+}
 
-	return f, nil
+// rejected reports whether err is the rejection of a response that is too
+// large or can't be trusted.
+func rejected(err error) bool {
+	var sizeErr *ResponseSizeError
+	return errors.As(err, &sizeErr) ||
+		errors.Is(err, ErrResponseSignature) ||
+		errors.Is(err, ErrMetadataMissing) ||
+		errors.Is(err, ErrMetadataExpired) ||
+		errors.Is(err, ErrMetadataRollback)
 }
-func checkResult(r io.Reader) (*CheckResponse, error) {
-	var result CheckResponse
-	if err := json.NewDecoder(r).Decode(&result); err != nil {
-		return nil, err
+
+// cacheKey returns the key of the check in the CacheFile. Verified
+// responses are cached along with their signature, and unverified ones
+// must not be used once PublicKeys are set, so they have different keys.
+func (p *CheckParams) cacheKey() string {
+	key := p.Version
+	if q, _ := p.extraQuery(); len(q) > 0 {
+		key += "\x00" + q.Encode()
 	}
-	return &result, nil
+	if len(p.PublicKeys) > 0 {
+		key += "\x00signed"
+	}
+	return key
 }
 
-func checkSignature(path string) (string, error) {
-	_, err := os.Stat(path)
-	if err == nil {
-		// The file exists, read it out
-		sigBytes, err := os.ReadFile(path)
-		if err != nil {
-			return "", err
-		}
+// ErrReservedQueryKey is returned by Check if CheckParams.Extra holds an
+// empty key or a key that Check sets itself.
+var ErrReservedQueryKey = errors.New("checkpoint: reserved query parameter")
+
+// reservedQueryKeys are the query parameters that Extra can't set.
+var reservedQueryKeys = map[string]bool{
+	"version":   true,
+	"arch":      true,
+	"os":        true,
+	"signature": true,
+	"channel":   true,
+	"edition":   true,
+}
 
-		// Split the file into lines
-		lines := strings.SplitN(string(sigBytes), "\n", 2)
-		if len(lines) > 0 {
-			return strings.TrimSpace(lines[0]), nil
+// extraQuery returns the query parameters for Channel, Edition and Extra.
+func (p *CheckParams) extraQuery() (url.Values, error) {
+	v := url.Values{}
+	for k, val := range p.Extra {
+		if k == "" || reservedQueryKeys[strings.ToLower(k)] {
+			return nil, fmt.Errorf("%w %q", ErrReservedQueryKey, k)
 		}
+		v.Set(k, val)
+	}
+	if p.Channel != "" {
+		v.Set("channel", p.Channel)
 	}
+	if p.Edition != "" {
+		v.Set("edition", p.Edition)
+	}
+	return v, nil
+}
+
+// fileSignatureStore returns the store for the SignatureFile, or nil if
+// there is none.
+func (p *CheckParams) fileSignatureStore() *FileSignatureStore {
+	if p.SignatureFile == "" {
+		return nil
+	}
+	return &FileSignatureStore{Path: p.SignatureFile, FileMode: p.FileMode, DirMode: p.DirMode}
+}
 
-	// If this isn't a non-exist error, then return that.
-	if !os.IsNotExist(err) {
-		return "", err
+// acknowledgedAlerts returns the IDs of the alerts acknowledged in the
+// signature store, if it keeps track of them.
+func (p *CheckParams) acknowledgedAlerts() ([]int, error) {
+	store := p.SignatureStore
+	if file := p.fileSignatureStore(); store == nil && file != nil {
+		store = file
 	}
 
-	// The file doesn't exist, so create a signature.
-	var b [16]byte
-	n := 0
-	for n < 16 {
-		n2, err := crand.Read(b[n:])
-		if err != nil {
-			return "", err
-		}
+	a, ok := store.(interface{ Acknowledged() ([]int, error) })
+	if !ok {
+		return nil, nil
+	}
+	return a.Acknowledged()
+}
 
-		n += n2
+// clone returns a deep copy of the response.
+func (r *CheckResponse) clone() *CheckResponse {
+	if r == nil {
+		return nil
 	}
-	signature := fmt.Sprintf(
-		"%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 
-	// Make sure the directory holding our signature exists.
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return "", err
+	c := *r
+	if r.Alerts != nil {
+		c.Alerts = make([]*CheckAlert, len(r.Alerts))
+		for i, a := range r.Alerts {
+			alert := *a
+			c.Alerts[i] = &alert
+		}
+	}
+	if r.Metadata != nil {
+		m := *r.Metadata
+		c.Metadata = &m
 	}
+	return &c
+}
 
-	// Write the signature
-	if err := os.WriteFile(path, []byte(signature+"\n\n"+userMessage+"\n"), 0644); err != nil {
-		return "", err
+// filterAlerts removes the alerts with the given IDs from the response.
+func (r *CheckResponse) filterAlerts(ids []int) {
+	if len(ids) == 0 || len(r.Alerts) == 0 {
+		return
 	}
 
-	return signature, nil
+	skip := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		skip[id] = true
+	}
+
+	alerts := make([]*CheckAlert, 0, len(r.Alerts))
+	for _, a := range r.Alerts {
+		if !skip[a.ID] {
+			alerts = append(alerts, a)
+		}
+	}
+	r.Alerts = alerts
 }
 
-func writeCacheHeader(f io.Writer, v string) error {
-	// Write our signature first
-	if err := binary.Write(f, binary.LittleEndian, magicBytes); err != nil {
-		return err
+// CheckInterval is used to check for a response on a given interval duration.
+// The interval is not exact, and checks are randomized to prevent a thundering
+// herd. However, it is expected that on average one check is performed per
+// interval. The returned channel may be closed to stop background checks.
+func CheckInterval(p *CheckParams, interval time.Duration, cb func(*CheckResponse, error)) chan struct{} {
+	doneCh := make(chan struct{})
+
+	if !policyOrEnv(p.Policy).Allowed(CapabilityCheck, p.Force) {
+		return doneCh
 	}
 
-	// Write out our current version length
-	length := uint32(len(v))
-	if err := binary.Write(f, binary.LittleEndian, length); err != nil {
-		return err
+	clock := clockOrSystem(p.Clock)
+	go func() {
+		for {
+			select {
+			case <-clock.After(randomStagger(p.RandSource, interval)):
+				resp, err := Check(p)
+				cb(resp, err)
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	return doneCh
+}
+
+// randomStagger returns an interval that is between 3/4 and 5/4 of
+// the given interval. The expected value is the interval. If src is nil,
+// the global random source is used.
+func randomStagger(src mrand.Source, interval time.Duration) time.Duration {
+	var n int64
+	if src != nil {
+		n = src.Int63()
+	} else {
+		n = mrand.Int63()
 	}
 
-	_, err := f.Write([]byte(v))
-	return err
+	stagger := time.Duration(n) % (interval / 2)
+	return 3*(interval/4) + stagger
 }
 
-// userMessage is suffixed to the signature file to provide feedback.
-var userMessage = `
-This signature is a randomly generated UUID used to de-duplicate
-alerts and version information. This signature is random, it is
-not based on any personally identifiable information. To create
-a new signature, you can simply delete this file at any time.
-See the documentation for the software using Checkpoint for more
-information on how to disable it.
-`
+func checkResult(r io.Reader, strict bool) (*CheckResponse, error) {
+	var result CheckResponse
+	if err := decodeJSON(r, &result, strict); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -4,8 +4,10 @@
 package checkpoint
 
 import (
+	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -227,6 +229,83 @@ func TestCheck_cacheNested(t *testing.T) {
 	}
 }
 
+func TestCheck_extra(t *testing.T) {
+	dir := t.TempDir()
+
+	var queries []url.Values
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			queries = append(queries, req.URL.Query())
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	check := func(extra map[string]string) {
+		t.Helper()
+		_, err := Check(&CheckParams{
+			Product:    "test",
+			Version:    "1.0",
+			Channel:    "beta",
+			Edition:    "enterprise",
+			Extra:      extra,
+			CacheFile:  filepath.Join(dir, "cache"),
+			HTTPClient: mockClient,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	check(map[string]string{"installer": "brew"})
+	check(map[string]string{"installer": "brew"})
+	if len(queries) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(queries))
+	}
+	q := queries[0]
+	for k, v := range map[string]string{
+		"version":   "1.0",
+		"channel":   "beta",
+		"edition":   "enterprise",
+		"installer": "brew",
+	} {
+		if got := q.Get(k); got != v {
+			t.Fatalf("expected %s=%q, got %q", k, v, got)
+		}
+	}
+
+	// Different extras must not be served from the same cache.
+	check(map[string]string{"installer": "brew", "fips": "true"})
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(queries))
+	}
+	if got := queries[1].Get("fips"); got != "true" {
+		t.Fatalf("expected fips=true, got %q", got)
+	}
+}
+
+func TestCheck_extraReserved(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			t.Fatal("unexpected request")
+			return nil, nil
+		}),
+	}
+	for _, key := range []string{"", "version", "Signature", "channel"} {
+		_, err := Check(&CheckParams{
+			Product:    "test",
+			Version:    "1.0",
+			Extra:      map[string]string{key: "x"},
+			HTTPClient: mockClient,
+		})
+		if !errors.Is(err, ErrReservedQueryKey) {
+			t.Fatalf("key %q: expected ErrReservedQueryKey, got %v", key, err)
+		}
+	}
+}
+
 func TestCheckInterval(t *testing.T) {
 	expected := &CheckResponse{
 		Product:             "test",
@@ -286,13 +365,26 @@ func TestCheckInterval(t *testing.T) {
 		}
 	}
 
+	clock := NewFakeClock(time.Now())
 	params.HTTPClient = mockClient
-	doneCh := CheckInterval(params, 10*time.Millisecond, checkFn)
+	params.Clock = clock
+	params.RandSource = fixedSource(0)
+	doneCh := CheckInterval(params, 24*time.Hour, checkFn)
 	defer close(doneCh)
 
+	// The check is scheduled 3/4 of the interval out with our source.
+	clock.BlockUntil(1)
+	clock.Advance(18*time.Hour - 1)
 	select {
 	case <-calledCh:
-	case <-time.After(500 * time.Millisecond):
+		t.Fatal("expected no check before the interval")
+	default:
+	}
+	clock.Advance(1)
+
+	select {
+	case <-calledCh:
+	case <-time.After(5 * time.Second):
 		t.Fatalf("timeout")
 	}
 }
@@ -327,14 +419,27 @@ func TestCheckInterval_disabled(t *testing.T) {
 	}
 }
 
+// fixedSource is a math/rand.Source that always returns n.
+type fixedSource int64
+
+func (s fixedSource) Int63() int64 { return int64(s) }
+func (s fixedSource) Seed(int64)   {}
+
 func TestRandomStagger(t *testing.T) {
 	intv := 24 * time.Hour
-	min := 18 * time.Hour
-	max := 30 * time.Hour
-	for i := 0; i < 1000; i++ {
-		out := randomStagger(intv)
-		if out < min || out > max {
-			t.Fatalf("unexpected value: %v", out)
+	cases := []struct {
+		n        int64
+		expected time.Duration
+	}{
+		{0, 18 * time.Hour},
+		{int64(6 * time.Hour), 24 * time.Hour},
+		{int64(12*time.Hour) - 1, 30*time.Hour - 1},
+		{int64(12 * time.Hour), 18 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		if out := randomStagger(fixedSource(tc.n), intv); out != tc.expected {
+			t.Fatalf("%d: expected %v, got %v", tc.n, tc.expected, out)
 		}
 	}
 }
@@ -0,0 +1,230 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// ProxyError is returned, wrapped in a *url.Error, when a request fails
+// because of the proxy rather than the checkpoint server.
+//
+// The status of a refused CONNECT request is only known if the transport
+// is configured by checkpoint: if there is no HTTPClient in the params, or
+// TLS or proxy options are set. An injected transport is otherwise used
+// as is, and a refusal is reported the way it reports it.
+type ProxyError struct {
+	// Proxy is the URL of the proxy, without any password.
+	Proxy string
+
+	// StatusCode is the status the proxy answered the CONNECT request
+	// with, or zero if it couldn't be reached.
+	StatusCode int
+
+	Err error
+}
+
+func (e *ProxyError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("checkpoint: proxy %s refused to connect: %d %s",
+			e.Proxy, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("checkpoint: proxy %s: %v", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// transportOptions are the options of the transport of a client. Log
+// receives a record when options that only come from the environment
+// can't be applied.
+type transportOptions struct {
+	TLS   *TLSOptions
+	Proxy func(*http.Request) (*url.URL, error)
+	Log   *slog.Logger
+}
+
+// proxy returns the proxy function to use, or nil to keep the one of the
+// transport. The CHECKPOINT_PROXY environment variable is used if there is
+// no Proxy. Like HTTPS_PROXY, it is ignored for hosts matched by NO_PROXY.
+func (o *transportOptions) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if o.Proxy != nil {
+		return o.Proxy, nil
+	}
+
+	v := os.Getenv("CHECKPOINT_PROXY")
+	if v == "" {
+		return nil, nil
+	}
+	if !strings.Contains(v, "://") {
+		v = "http://" + v
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: bad CHECKPOINT_PROXY: %w", err)
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(noProxy, req.URL.Hostname()) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// matchNoProxy reports whether host is matched by the comma-separated
+// NO_PROXY list, which holds "*", domains that also match their subdomains,
+// IP addresses and CIDR ranges. Ports are ignored.
+func matchNoProxy(noProxy, host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+
+		switch {
+		case entry == "":
+		case entry == "*":
+			return true
+		case ip != nil:
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(ip) {
+				return true
+			}
+			if ip.Equal(net.ParseIP(entry)) {
+				return true
+			}
+		default:
+			entry = strings.TrimPrefix(entry, ".")
+			if host == entry || strings.HasSuffix(host, "."+entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newHTTPClient returns a copy of c, or a new client if c is nil, that
+// uses the options and returns a ProxyError for failures of the proxy.
+// Changing the returned client, such as its timeout, doesn't affect c.
+//
+// Without options, the transport of c is used as is, so that its
+// connections are reused. Otherwise it is cloned, which is only possible
+// for an *http.Transport. As the clone is only used for a single request,
+// it doesn't keep connections alive. Options that only come from the
+// environment are ignored for other transports, so that setting an
+// environment variable can't break a product that wraps its transport.
+func newHTTPClient(c *http.Client, o transportOptions) (*http.Client, error) {
+	proxy, err := o.proxy()
+	if err != nil {
+		return nil, err
+	}
+
+	// The transport of the default client is new, so it can be changed.
+	owned := c == nil
+	if owned {
+		c = cleanhttp.DefaultClient()
+	}
+	cp := *c
+	client := &cp
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !owned && proxy == nil && !o.TLS.configured() {
+		client.Transport = &proxyTransport{RoundTripper: base, proxy: transportProxy(base)}
+		return client, nil
+	}
+
+	t, ok := base.(*http.Transport)
+	if !ok {
+		if o.Proxy != nil || o.TLS.explicit() {
+			return nil, fmt.Errorf("checkpoint: TLS and proxy options can't be applied to transport %T", base)
+		}
+		logger(o.Log).Warn("ignoring CHECKPOINT_CA_FILE and CHECKPOINT_PROXY for custom transport",
+			"transport", fmt.Sprintf("%T", base))
+		client.Transport = &proxyTransport{RoundTripper: base, proxy: transportProxy(base)}
+		return client, nil
+	}
+	if !owned {
+		t = t.Clone()
+		t.DisableKeepAlives = true
+	}
+
+	if o.TLS.configured() {
+		if t.TLSClientConfig, err = o.TLS.Config(); err != nil {
+			return nil, err
+		}
+	}
+	if proxy != nil {
+		t.Proxy = proxy
+	}
+	next := t.OnProxyConnectResponse
+	t.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, req *http.Request, resp *http.Response) error {
+		if next != nil {
+			if err := next(ctx, proxyURL, req, resp); err != nil {
+				return err
+			}
+		}
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		return &ProxyError{
+			Proxy:      proxyURL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Err:        errors.New(resp.Status),
+		}
+	}
+	client.Transport = &proxyTransport{RoundTripper: t, proxy: t.Proxy}
+
+	return client, nil
+}
+
+// transportProxy returns the proxy function of rt, if it has one.
+func transportProxy(rt http.RoundTripper) func(*http.Request) (*url.URL, error) {
+	if t, ok := rt.(*http.Transport); ok {
+		return t.Proxy
+	}
+	return nil
+}
+
+// proxyTransport turns failures to reach the proxy into a ProxyError.
+type proxyTransport struct {
+	http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+
+	var opErr *net.OpError
+	var proxyErr *ProxyError
+	if err != nil && !errors.As(err, &proxyErr) && errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		proxy := ""
+		if t.proxy != nil {
+			if u, _ := t.proxy(req); u != nil {
+				proxy = u.Redacted()
+			}
+		}
+		err = &ProxyError{Proxy: proxy, Err: err}
+	}
+	return resp, err
+}
@@ -0,0 +1,291 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newForwardProxy starts a local proxy that tunnels CONNECT requests to
+// addr, if they carry the given Proxy-Authorization. It returns the URL of
+// the proxy and a function returning the hosts it tunneled to.
+func newForwardProxy(t *testing.T, addr, auth string) (*url.URL, func() []string) {
+	t.Helper()
+
+	var lock sync.Mutex
+	var hosts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		lock.Lock()
+		hosts = append(hosts, r.Host)
+		lock.Unlock()
+
+		upstream, err := dialAddr(addr)(r.Context(), "tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	return u, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), hosts...)
+	}
+}
+
+func TestCheck_proxy(t *testing.T) {
+	addr, caFile, _ := newTLSServer(t, 0)
+	proxyURL, hosts := newForwardProxy(t, addr, "Basic dXNlcjpwYXNz")
+
+	check := func(proxy func(*http.Request) (*url.URL, error)) error {
+		_, err := Check(&CheckParams{
+			Product:              "test",
+			Version:              "1.0",
+			DisableDeduplication: true,
+			Policy:               &Policy{},
+			TLS:                  &TLSOptions{CAFile: caFile},
+			Proxy:                proxy,
+		})
+		return err
+	}
+
+	withAuth := *proxyURL
+	withAuth.User = url.UserPassword("user", "pass")
+	if err := check(http.ProxyURL(&withAuth)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if h := hosts(); len(h) != 1 || h[0] != "checkpoint-api.hashicorp.com:443" {
+		t.Fatalf("expected the request to go through the proxy, got %v", h)
+	}
+
+	// The proxy refuses the wrong credentials.
+	withAuth.User = url.UserPassword("user", "wrong")
+	var proxyErr *ProxyError
+	if err := check(http.ProxyURL(&withAuth)); !errors.As(err, &proxyErr) || proxyErr.StatusCode != http.StatusProxyAuthRequired {
+		t.Fatalf("expected a ProxyError with status 407, got %v", err)
+	}
+	if proxyErr.Proxy != "http://user:xxxxx@"+proxyURL.Host {
+		t.Fatalf("expected a redacted proxy URL, got %q", proxyErr.Proxy)
+	}
+
+	// A proxy that can't be reached is reported as such.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	closed := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	_ = l.Close()
+	if err := check(http.ProxyURL(closed)); !errors.As(err, &proxyErr) || proxyErr.StatusCode != 0 {
+		t.Fatalf("expected a ProxyError, got %v", err)
+	}
+}
+
+func TestCheck_proxyEnv(t *testing.T) {
+	addr, caFile, _ := newTLSServer(t, 0)
+	proxyURL, hosts := newForwardProxy(t, addr, "")
+
+	// Connections to anything but the proxy go to the server.
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, a string) (net.Conn, error) {
+			if a == proxyURL.Host {
+				return dialAddr(a)(ctx, network, a)
+			}
+			return dialAddr(addr)(ctx, network, a)
+		},
+	}
+
+	check := func() error {
+		_, err := CheckContext(context.Background(), &CheckParams{
+			Product:              "test",
+			Version:              "1.0",
+			DisableDeduplication: true,
+			Policy:               &Policy{},
+			TLS:                  &TLSOptions{CAFile: caFile},
+			HTTPClient:           &http.Client{Transport: transport},
+		})
+		return err
+	}
+
+	t.Setenv("CHECKPOINT_PROXY", proxyURL.Host)
+	t.Setenv("NO_PROXY", "example.com")
+	if err := check(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(hosts()) != 1 {
+		t.Fatalf("expected the request to go through the proxy, got %v", hosts())
+	}
+
+	// With NO_PROXY matching the checkpoint host, the proxy is skipped.
+	t.Setenv("NO_PROXY", "example.com,.hashicorp.com")
+	if err := check(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(hosts()) != 1 {
+		t.Fatalf("expected the proxy to be skipped, got %v", hosts())
+	}
+}
+
+func TestNewHTTPClient_reusesTransport(t *testing.T) {
+	t.Setenv("CHECKPOINT_PROXY", "")
+
+	transport := &http.Transport{}
+	c := &http.Client{Transport: transport}
+	client, err := newHTTPClient(c, transportOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if client == c {
+		t.Fatal("expected a copy of the client")
+	}
+	if pt, ok := client.Transport.(*proxyTransport); !ok || pt.RoundTripper != transport {
+		t.Fatalf("expected the transport to be used as is, got %#v", client.Transport)
+	}
+}
+
+func TestNewHTTPClient_customTransportEnvTLS(t *testing.T) {
+	t.Setenv("CHECKPOINT_PROXY", "")
+	t.Setenv("CHECKPOINT_CA_FILE", "/nonexistent/ca.pem")
+
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("unused")
+	})
+	c := &http.Client{Transport: rt}
+
+	// CHECKPOINT_CA_FILE alone is ignored for a custom transport.
+	client, err := newHTTPClient(c, transportOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if pt, ok := client.Transport.(*proxyTransport); !ok || pt.RoundTripper == nil {
+		t.Fatalf("expected the transport to be used as is, got %#v", client.Transport)
+	}
+
+	// Explicit options still can't be applied to it.
+	_, err = newHTTPClient(c, transportOptions{TLS: &TLSOptions{MinVersion: tls.VersionTLS13}})
+	if err == nil {
+		t.Fatal("expected an error for explicit TLS options")
+	}
+}
+
+func TestCheck_customTransportEnvProxy(t *testing.T) {
+	t.Setenv("CHECKPOINT_PROXY", "http://127.0.0.1:1")
+
+	var hosts []string
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"product":"test","current_version":"1.0"}`)),
+		}, nil
+	})
+	params := &CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		DisableDeduplication: true,
+		Policy:               &Policy{},
+		HTTPClient:           &http.Client{Transport: rt},
+	}
+
+	// CHECKPOINT_PROXY alone is ignored for a custom transport.
+	if _, err := Check(params); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("expected the transport to be used, got %v", hosts)
+	}
+
+	// An explicit proxy still can't be applied to it.
+	params.Proxy = http.ProxyFromEnvironment
+	if _, err := Check(params); err == nil {
+		t.Fatal("expected an error for an explicit proxy")
+	}
+}
+
+func TestCheck_proxyHook(t *testing.T) {
+	addr, caFile, _ := newTLSServer(t, 0)
+	proxyURL, _ := newForwardProxy(t, addr, "Basic dXNlcjpwYXNz")
+
+	var statuses []int
+	transport := &http.Transport{
+		OnProxyConnectResponse: func(ctx context.Context, proxyURL *url.URL, req *http.Request, resp *http.Response) error {
+			statuses = append(statuses, resp.StatusCode)
+			return nil
+		},
+	}
+	_, err := Check(&CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		DisableDeduplication: true,
+		Policy:               &Policy{},
+		TLS:                  &TLSOptions{CAFile: caFile},
+		Proxy:                http.ProxyURL(proxyURL),
+		HTTPClient:           &http.Client{Transport: transport},
+	})
+
+	var proxyErr *ProxyError
+	if !errors.As(err, &proxyErr) || proxyErr.StatusCode != http.StatusProxyAuthRequired {
+		t.Fatalf("expected a ProxyError with status 407, got %v", err)
+	}
+	if len(statuses) != 1 || statuses[0] != http.StatusProxyAuthRequired {
+		t.Fatalf("expected the existing hook to be called, got %v", statuses)
+	}
+}
+
+func TestMatchNoProxy(t *testing.T) {
+	cases := []struct {
+		noProxy string
+		host    string
+		match   bool
+	}{
+		{"", "checkpoint-api.hashicorp.com", false},
+		{"*", "checkpoint-api.hashicorp.com", true},
+		{"hashicorp.com", "checkpoint-api.hashicorp.com", true},
+		{".hashicorp.com", "checkpoint-api.hashicorp.com", true},
+		{"corp.com", "checkpoint-api.hashicorp.com", false},
+		{"a.com, checkpoint-api.hashicorp.com:443", "checkpoint-api.hashicorp.com", true},
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.0/8", "192.168.0.1", false},
+	}
+	for _, tc := range cases {
+		if actual := matchNoProxy(tc.noProxy, tc.host); actual != tc.match {
+			t.Errorf("matchNoProxy(%q, %q) = %t", tc.noProxy, tc.host, actual)
+		}
+	}
+}
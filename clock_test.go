@@ -0,0 +1,122 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	short := c.After(time.Minute)
+	long := c.After(time.Hour)
+	now := c.After(0)
+
+	select {
+	case <-now:
+	default:
+		t.Fatal("expected zero duration timer to fire immediately")
+	}
+
+	c.BlockUntil(2)
+	c.Advance(time.Minute)
+	if got := <-short; !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("unexpected time: %v", got)
+	}
+	select {
+	case <-long:
+		t.Fatal("expected long timer not to fire yet")
+	default:
+	}
+
+	c.Advance(time.Hour)
+	<-long
+	if !c.Now().Equal(start.Add(time.Hour + time.Minute)) {
+		t.Fatalf("unexpected time: %v", c.Now())
+	}
+}
+
+func TestCheck_cacheExpiry(t *testing.T) {
+	requests := 0
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	// The clock is far from the wall clock, expiry must only follow it.
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	params := &CheckParams{
+		Product:    "test",
+		Version:    "1.0",
+		CacheFile:  filepath.Join(t.TempDir(), "cache"),
+		HTTPClient: mockClient,
+		Clock:      clock,
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := Check(params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	clock.Advance(defaultCacheDuration + time.Minute)
+	if _, err := Check(params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected expired cache to cause a request, got %d", requests)
+	}
+}
+
+func TestCacheFile_legacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+
+	// Files written before the header held the write time fall back to
+	// the modification time.
+	var b bytes.Buffer
+	b.Write(legacyMagicBytes[:])
+	b.Write([]byte{3, 0, 0, 0})
+	b.WriteString("1.0")
+	b.WriteString(`{"product": "test"}`)
+	if err := os.WriteFile(path, b.Bytes(), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache := &cacheFile{Path: path, Key: "1.0"}
+	r, err := cache.open(false)
+	if err != nil || r == nil {
+		t.Fatalf("expected the legacy cache to be used, got %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(body) != `{"product": "test"}` {
+		t.Fatalf("unexpected body: %q", body)
+	}
+
+	old := time.Now().Add(-2 * defaultCacheDuration)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, err := cache.open(false); err != nil || r != nil {
+		t.Fatalf("expected the old legacy cache to be expired, got %v", err)
+	}
+}
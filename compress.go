@@ -0,0 +1,153 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultCompressionMinSize is the body size below which compression is
+// skipped by default. Small bodies usually grow when compressed.
+const defaultCompressionMinSize = 1024
+
+// Compression configures compression of telemetry request bodies.
+//
+// If the server doesn't support the encoding and answers with
+// 415 Unsupported Media Type, Report retries once without compression.
+type Compression struct {
+	// Encoding is the content coding sent in the Content-Encoding header.
+	// It defaults to "gzip".
+	Encoding string
+
+	// MinSize is the body size in bytes below which bodies are sent
+	// uncompressed. It defaults to 1 KiB. A negative MinSize compresses
+	// every body.
+	MinSize int
+
+	// Encoder wraps w in a writer that compresses with Encoding. It is
+	// only optional for gzip, and allows other encodings such as zstd to
+	// be used without this package depending on an implementation:
+	//
+	//	Encoder: func(w io.Writer) (io.WriteCloser, error) {
+	//		return zstd.NewWriter(w)
+	//	}
+	Encoder func(w io.Writer) (io.WriteCloser, error)
+}
+
+// compress returns the compressed body and its encoding. If b shouldn't
+// be compressed, it is returned as-is with an empty encoding.
+func (c *Compression) compress(b []byte) ([]byte, string, error) {
+	if c == nil {
+		return b, "", nil
+	}
+
+	minSize := c.MinSize
+	if minSize == 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if len(b) < minSize {
+		return b, "", nil
+	}
+
+	encoding := c.Encoding
+	if encoding == "" {
+		encoding = "gzip"
+	}
+	encoder := c.Encoder
+	if encoder == nil {
+		if encoding != "gzip" {
+			return nil, "", fmt.Errorf("no encoder for content encoding %q", encoding)
+		}
+		encoder = func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := encoder(&buf)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := w.Write(b); err != nil {
+		_ = w.Close()
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), encoding, nil
+}
+
+// Decoder wraps r in a reader that decompresses a content coding, such as
+// brotli or zstd.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// builtinDecoders are the content codings that can always be decoded.
+var builtinDecoders = map[string]Decoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// acceptEncoding returns the Accept-Encoding header announcing the
+// decoders and the built-in codings, in that order of preference.
+func acceptEncoding(decoders map[string]Decoder) string {
+	var encodings []string
+	for e := range decoders {
+		if _, ok := builtinDecoders[e]; !ok {
+			encodings = append(encodings, e)
+		}
+	}
+	sort.Strings(encodings)
+
+	return strings.Join(append(encodings, "gzip", "deflate"), ", ")
+}
+
+// decodeBody wraps r in readers decoding the content codings of the
+// Content-Encoding header, which lists them in the order they were
+// applied. The returned function closes the decoders.
+func decodeBody(r io.Reader, contentEncoding string, decoders map[string]Decoder) (io.Reader, func(), error) {
+	var closers []io.Closer
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			_ = closers[i].Close()
+		}
+	}
+
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+
+		decoder, ok := decoders[coding]
+		if !ok {
+			decoder, ok = builtinDecoders[coding]
+		}
+		if !ok {
+			closeAll()
+			return nil, nil, fmt.Errorf("checkpoint: unsupported content encoding %q", coding)
+		}
+
+		rc, err := decoder(r)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("checkpoint: can't decode %s response: %w", coding, err)
+		}
+		closers = append(closers, rc)
+		r = rc
+	}
+	return r, closeAll, nil
+}
@@ -0,0 +1,122 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck_compressedResponse(t *testing.T) {
+	body := `{"product": "test", "current_version": "1.0.2"}`
+
+	var gotEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasPrefix(gotEncoding, "x-base64"):
+			w.Header().Set("Content-Encoding", "gzip, x-base64")
+			enc := base64.NewEncoder(base64.StdEncoding, w)
+			gz := gzip.NewWriter(enc)
+			_, _ = gz.Write([]byte(body))
+			_ = gz.Close()
+			_ = enc.Close()
+		default:
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, _ = gz.Write([]byte(body))
+			_ = gz.Close()
+		}
+	}))
+	defer server.Close()
+
+	// The client turns off the transparent gzip of the transport.
+	u, _ := url.Parse(server.URL)
+	transport := &http.Transport{DisableCompression: true}
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req.URL.Scheme = u.Scheme
+			req.URL.Host = u.Host
+			return transport.RoundTrip(req)
+		}),
+	}
+
+	tests := map[string]map[string]Decoder{
+		"gzip": nil,
+		"custom": {
+			"x-base64": func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+			},
+		},
+	}
+	for name, decoders := range tests {
+		t.Run(name, func(t *testing.T) {
+			cacheFile := filepath.Join(t.TempDir(), "cache")
+			resp, err := Check(&CheckParams{
+				Product:              "test",
+				Version:              "1.0",
+				CacheFile:            cacheFile,
+				Decoders:             decoders,
+				DisableDeduplication: true,
+				Policy:               &Policy{},
+				HTTPClient:           mockClient,
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if resp.CurrentVersion != "1.0.2" {
+				t.Fatalf("bad: %#v", resp)
+			}
+
+			if expected := acceptEncoding(decoders); gotEncoding != expected {
+				t.Fatalf("expected Accept-Encoding %q, got %q", expected, gotEncoding)
+			}
+
+			// The cache holds the decoded response.
+			b, err := os.ReadFile(cacheFile)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !bytes.HasSuffix(b, []byte(body)) {
+				t.Fatalf("expected a decoded cache, got %q", b)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte("deflated"))
+	_ = w.Close()
+
+	r, closeBody, err := decodeBody(&buf, "Deflate", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer closeBody()
+	if b, err := io.ReadAll(r); err != nil || string(b) != "deflated" {
+		t.Fatalf("bad: %q %v", b, err)
+	}
+
+	if _, _, err := decodeBody(strings.NewReader(""), "br", nil); err == nil {
+		t.Fatal("expected an error for an unsupported encoding")
+	}
+
+	if actual := acceptEncoding(map[string]Decoder{"zstd": nil, "br": nil, "gzip": nil}); actual != "br, zstd, gzip, deflate" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
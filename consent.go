@@ -0,0 +1,154 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// consentFileVersion is the version of the consent file format.
+const consentFileVersion = 1
+
+// consentFileName is the name of the consent file that ConsentFile places
+// next to the signature file.
+const consentFileName = "checkpoint_consent.json"
+
+// ErrConsentRequired is returned by Report when the policy requires
+// consent and the user hasn't made a decision for the current policy
+// version yet. CLIs should prompt the user and call RecordConsent.
+var ErrConsentRequired = errors.New("checkpoint: telemetry consent required")
+
+// ConsentDecision is the answer of a user to a telemetry consent prompt.
+type ConsentDecision string
+
+const (
+	ConsentUnknown ConsentDecision = ""
+	ConsentGranted ConsentDecision = "granted"
+	ConsentDenied  ConsentDecision = "denied"
+)
+
+// Consent is a recorded telemetry consent decision.
+type Consent struct {
+	// Version is the version of the file format.
+	Version int `json:"version"`
+
+	// Decision is what the user answered, and Time is when they did.
+	Decision ConsentDecision `json:"decision"`
+	Time     time.Time       `json:"time"`
+
+	// PolicyVersion is the version of the telemetry policy the user
+	// answered for. If the policy changes, the user must be asked again.
+	PolicyVersion string `json:"policy_version"`
+}
+
+// ConsentFile returns the path of the consent file that belongs next to
+// the given signature file.
+func ConsentFile(signatureFile string) string {
+	return filepath.Join(filepath.Dir(signatureFile), consentFileName)
+}
+
+// LoadConsent reads the consent file at path. If the file doesn't exist,
+// it returns nil and no error.
+func LoadConsent(path string) (*Consent, error) {
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var c Consent
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("invalid consent file %s: %w", path, err)
+	}
+	if c.Version > consentFileVersion {
+		return nil, fmt.Errorf("unsupported consent file version %d in %s", c.Version, path)
+	}
+
+	return &c, nil
+}
+
+// NeedsConsent reports whether a CLI must prompt the user for consent:
+// either no decision has been recorded at path, or it was recorded for a
+// different policy version.
+func NeedsConsent(path string, policyVersion string) (bool, error) {
+	c, err := LoadConsent(path)
+	if err != nil {
+		return false, err
+	}
+
+	return c == nil || c.Decision == ConsentUnknown || c.PolicyVersion != policyVersion, nil
+}
+
+// RecordConsent records the decision of the user for the given policy
+// version at path, replacing any previous decision.
+func RecordConsent(path string, d ConsentDecision, policyVersion string) (*Consent, error) {
+	if d != ConsentGranted && d != ConsentDenied {
+		return nil, fmt.Errorf("invalid consent decision %q", d)
+	}
+
+	c := &Consent{
+		Version:       consentFileVersion,
+		Decision:      d,
+		Time:          time.Now().UTC(),
+		PolicyVersion: policyVersion,
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the directory holding the consent file exists.
+	if err := mkdirFor(path, DefaultDirMode); err != nil {
+		return nil, err
+	}
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+
+	// Replace the file atomically so that a crash never leaves a
+	// half-written decision behind.
+	if err := writeFileAtomic(path, append(b, '\n'), DefaultFileMode); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// consent checks the consent requirement of the policy for a report.
+// It returns whether the report may be sent, or ErrConsentRequired if
+// no decision has been made yet.
+func (p *Policy) consent(signatureFile string) (bool, error) {
+	if p == nil || !p.RequireConsent {
+		return true, nil
+	}
+
+	path := p.ConsentFile
+	if path == "" && signatureFile != "" {
+		path = ConsentFile(signatureFile)
+	}
+	if path == "" {
+		return false, ErrConsentRequired
+	}
+
+	c, err := LoadConsent(path)
+	if err != nil {
+		return false, err
+	}
+	if c == nil || c.Decision == ConsentUnknown || c.PolicyVersion != p.PolicyVersion {
+		return false, ErrConsentRequired
+	}
+
+	return c.Decision == ConsentGranted, nil
+}
@@ -0,0 +1,97 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsent(t *testing.T) {
+	path := ConsentFile(filepath.Join(t.TempDir(), "nested", "signature"))
+
+	needs, err := NeedsConsent(path, "v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !needs {
+		t.Fatal("expected consent to be needed without a file")
+	}
+
+	if _, err := RecordConsent(path, ConsentGranted, "v1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := LoadConsent(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Version != consentFileVersion || c.Decision != ConsentGranted || c.PolicyVersion != "v1" || c.Time.IsZero() {
+		t.Fatalf("unexpected consent: %#v", c)
+	}
+
+	if needs, _ := NeedsConsent(path, "v1"); needs {
+		t.Fatal("expected consent not to be needed for the same policy")
+	}
+	if needs, _ := NeedsConsent(path, "v2"); !needs {
+		t.Fatal("expected consent to be needed for a new policy")
+	}
+
+	if _, err := RecordConsent(path, ConsentUnknown, "v1"); err == nil {
+		t.Fatal("expected error recording an unknown decision")
+	}
+}
+
+func TestReport_consent(t *testing.T) {
+	sigFile := filepath.Join(t.TempDir(), "signature")
+
+	sent := 0
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return &http.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	report := func() error {
+		return Report(context.Background(), &ReportParams{
+			SignatureFile: sigFile,
+			Product:       "test",
+			Policy:        &Policy{RequireConsent: true, PolicyVersion: "v1"},
+			HTTPClient:    mockClient,
+		})
+	}
+
+	if err := report(); !errors.Is(err, ErrConsentRequired) {
+		t.Fatalf("expected consent error, got %v", err)
+	}
+
+	if _, err := RecordConsent(ConsentFile(sigFile), ConsentDenied, "v1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := report(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 0 {
+		t.Fatalf("expected no reports without consent, got %d", sent)
+	}
+
+	if _, err := RecordConsent(ConsentFile(sigFile), ConsentGranted, "v1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := report(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("expected a report after consent, got %d", sent)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultFileMode and DefaultDirMode are the permissions of the files
+	// and directories this package creates, unless configured otherwise.
+	// They keep checkpoint state private to the current user.
+	DefaultFileMode os.FileMode = 0600
+	DefaultDirMode  os.FileMode = 0700
+)
+
+// UnsafeFileError is returned when a file or directory used for checkpoint
+// state can't be trusted, for example because it is a symbolic link or is
+// owned by another user.
+type UnsafeFileError struct {
+	Path   string
+	Reason string
+}
+
+func (e *UnsafeFileError) Error() string {
+	return fmt.Sprintf("checkpoint: refusing to use %s: %s", e.Path, e.Reason)
+}
+
+// fileMode returns m, or DefaultFileMode if m is zero.
+func fileMode(m os.FileMode) os.FileMode {
+	if m == 0 {
+		return DefaultFileMode
+	}
+	return m
+}
+
+// dirMode returns m, or DefaultDirMode if m is zero.
+func dirMode(m os.FileMode) os.FileMode {
+	if m == 0 {
+		return DefaultDirMode
+	}
+	return m
+}
+
+// checkFile verifies that the file at path, if it exists, is safe to use:
+// it must not be a symbolic link and must be owned by the current user.
+func checkFile(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return &UnsafeFileError{Path: path, Reason: "it is a symbolic link"}
+	}
+	return checkOwner(path, fi)
+}
+
+// mkdirFor makes sure the directory holding path exists and is safe to
+// use. Missing directories are created with the given permissions.
+func mkdirFor(path string, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	return checkFile(dir)
+}
+
+// createTemp creates a temporary file next to path, so that it can later
+// be renamed over path atomically.
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// writeFileTemp writes data to a new temporary file next to path and
+// returns its name.
+func writeFileTemp(path string, data []byte, perm os.FileMode) (string, error) {
+	f, err := createTemp(path, perm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeFileAtomic replaces the file at path with data. Readers see either
+// the old or the new content, never a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeFileTemp(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeFileExclusive writes data to path only if path doesn't exist yet.
+// The file appears with its full content at once. It returns false if the
+// file already existed, in which case it is left untouched.
+//
+// On filesystems without hard links, the file is created exclusively and
+// then written, so readers may briefly see it empty or partially written.
+func writeFileExclusive(path string, data []byte, perm os.FileMode) (bool, error) {
+	tmp, err := writeFileTemp(path, data, perm)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
+	// Unlike rename, link fails if the target exists.
+	err = linkFile(tmp, path)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsExist(err):
+		return false, nil
+	case errors.Is(err, errors.ErrUnsupported) || errors.Is(err, os.ErrPermission):
+		return createExclusive(path, data, perm)
+	default:
+		return false, err
+	}
+}
+
+// linkFile is os.Link, replaced in tests to simulate filesystems without
+// hard links.
+var linkFile = os.Link
+
+// createExclusive is the fallback of writeFileExclusive for filesystems
+// without hard links.
+func createExclusive(path string, data []byte, perm os.FileMode) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return false, err
+	}
+	return true, nil
+}
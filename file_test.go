@@ -0,0 +1,229 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileSignatureStore_concurrentCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "signature")
+
+	const n = 50
+	sigs := make([]string, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			sigs[i], errs[i] = resolveSignature("", nil, &FileSignatureStore{Path: path}, logger(nil))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if sigs[i] != sigs[0] {
+			t.Fatalf("expected all signatures to be %q, got %q", sigs[0], sigs[i])
+		}
+	}
+
+	if sig, err := (&FileSignatureStore{Path: path}).Load(); err != nil || sig != sigs[0] {
+		t.Fatalf("expected stored signature %q, got %q (%v)", sigs[0], sig, err)
+	}
+}
+
+func TestFileSignatureStore_concurrentRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signature")
+	f := &signatureFile{signature: "expired", created: time.Now().Add(-48 * time.Hour)}
+	if err := os.WriteFile(path, f.bytes(), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const n = 50
+	sigs := make([]string, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			sigs[i], errs[i] = (&FileSignatureStore{Path: path, RotateAfter: 24 * time.Hour}).Load()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if sigs[i] == "expired" || sigs[i] != sigs[0] {
+			t.Fatalf("expected all signatures to be rotated to %q, got %q", sigs[0], sigs[i])
+		}
+	}
+
+	if sig, err := (&FileSignatureStore{Path: path}).Load(); err != nil || sig != sigs[0] {
+		t.Fatalf("expected stored signature %q, got %q (%v)", sigs[0], sig, err)
+	}
+}
+
+func TestCheck_concurrentCache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "cache")
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test", "current_version": "1.0.2"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	const n = 20
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				resp, err := Check(&CheckParams{
+					Product:    "test",
+					Version:    "1.0",
+					CacheFile:  cacheFile,
+					HTTPClient: mockClient,
+				})
+				if err == nil && resp.CurrentVersion != "1.0.2" {
+					err = io.ErrUnexpectedEOF
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestCheck_fileModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	cacheFile := filepath.Join(dir, "cache")
+	sigFile := filepath.Join(dir, "signature")
+
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	_, err := Check(&CheckParams{
+		Product:       "test",
+		Version:       "1.0",
+		CacheFile:     cacheFile,
+		SignatureFile: sigFile,
+		HTTPClient:    mockClient,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	for path, mode := range map[string]os.FileMode{
+		dir:       DefaultDirMode,
+		cacheFile: DefaultFileMode,
+		sigFile:   DefaultFileMode,
+	} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fi.Mode().Perm() != mode {
+			t.Fatalf("expected %s to have mode %v, got %v", path, mode, fi.Mode().Perm())
+		}
+	}
+}
+
+func TestCheckFile_symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "signature")
+	if err := os.WriteFile(target, []byte("sig\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	_, err := (&FileSignatureStore{Path: link}).Load()
+	var unsafe *UnsafeFileError
+	if !errors.As(err, &unsafe) || unsafe.Path != link {
+		t.Fatalf("expected UnsafeFileError for %s, got %v", link, err)
+	}
+
+	_, err = Check(&CheckParams{
+		Product:   "test",
+		Version:   "1.0",
+		Signature: "sig",
+		CacheFile: link,
+	})
+	if !errors.As(err, &unsafe) {
+		t.Fatalf("expected UnsafeFileError for the cache, got %v", err)
+	}
+}
+
+func TestFileSignatureStore_noHardLinks(t *testing.T) {
+	link := linkFile
+	linkFile = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+	}
+	defer func() {
+		linkFile = link
+	}()
+
+	path := filepath.Join(t.TempDir(), "signature")
+	s := &FileSignatureStore{Path: path}
+	sig, err := s.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, err := s.Create(); err != nil || again != sig {
+		t.Fatalf("expected the signature to be kept, got %q (%v)", again, err)
+	}
+	if created, err := writeFileExclusive(path, []byte("other\n"), 0600); err != nil || created {
+		t.Fatalf("expected the existing file to be kept, got %v (%v)", created, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup de-duplicates concurrent calls with the same key, so that
+// only one of them does the work and the others share its result.
+type flightGroup[T any] struct {
+	lock  sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// flightCall is a call in flight or completed. done is closed once the
+// call has completed.
+type flightCall[T any] struct {
+	done   chan struct{}
+	val    T
+	err    error
+	panicv interface{}
+	dups   int
+}
+
+// do calls fn, unless a call with the same key is already in flight, in
+// which case it waits for that call and returns its result.
+//
+// fn runs in its own goroutine, so that it outlives its callers: every
+// caller, including the one that started it, stops waiting and returns
+// the error of ctx once ctx is done. A panic in fn is raised again in the
+// callers that are still waiting.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.dups++
+	} else {
+		c = &flightCall[T]{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.lock.Unlock()
+
+	select {
+	case <-c.done:
+		if c.panicv != nil {
+			panic(c.panicv)
+		}
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// run calls fn for c and releases its waiters.
+func (g *flightGroup[T]) run(key string, c *flightCall[T], fn func() (T, error)) {
+	defer func() {
+		c.panicv = recover()
+
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+}
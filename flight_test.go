@@ -0,0 +1,240 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForDups waits until n callers are waiting on the in-flight check.
+func waitForDups(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		checkFlights.lock.Lock()
+		dups := 0
+		for _, c := range checkFlights.calls {
+			dups += c.dups
+		}
+		checkFlights.lock.Unlock()
+
+		if dups >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d callers", n)
+}
+
+func TestCheck_deduplication(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test", "alerts": [{"id": 1}]}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	const n = 10
+	resps := make([]*CheckResponse, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i], errs[i] = Check(&CheckParams{
+				Product:    "dedupe",
+				Version:    "1.0",
+				HTTPClient: mockClient,
+			})
+		}(i)
+	}
+
+	waitForDups(t, n-1)
+	close(release)
+	wg.Wait()
+
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if resps[i].Product != "test" || len(resps[i].Alerts) != 1 {
+			t.Fatalf("unexpected response: %#v", resps[i])
+		}
+		if i > 0 && (resps[i] == resps[0] || resps[i].Alerts[0] == resps[0].Alerts[0]) {
+			t.Fatal("expected every caller to get its own copy")
+		}
+	}
+}
+
+func TestCheck_deduplicationDisabled(t *testing.T) {
+	var requests int32
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Check(&CheckParams{
+				Product:              "dedupe",
+				Version:              "1.0",
+				HTTPClient:           mockClient,
+				DisableDeduplication: true,
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if requests != n {
+		t.Fatalf("expected %d requests, got %d", n, requests)
+	}
+}
+
+func TestCheck_deduplicationCanceled(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	params := func() *CheckParams {
+		return &CheckParams{
+			Product:    "dedupe-canceled",
+			Version:    "1.0",
+			Policy:     &Policy{},
+			HTTPClient: mockClient,
+		}
+	}
+
+	// Another caller shares the request and isn't canceled.
+	var resp *CheckResponse
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err = Check(params())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := CheckContext(ctx, params()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("expected the deadline to stop waiting, took %s", d)
+	}
+
+	waitForDups(t, 1)
+	close(release)
+	<-done
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Product != "test" {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestCheck_deduplicationValidation(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	params := func(requireMetadata bool) *CheckParams {
+		return &CheckParams{
+			Product:         "dedupe-validation",
+			Version:         "1.0",
+			Policy:          &Policy{},
+			RequireMetadata: requireMetadata,
+			HTTPClient:      mockClient,
+		}
+	}
+
+	var weakErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, weakErr = Check(params(false))
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A check that validates more strictly doesn't share the request.
+	strictDone := make(chan error)
+	go func() {
+		_, err := Check(params(true))
+		strictDone <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&requests) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	<-done
+	if weakErr != nil {
+		t.Fatalf("unexpected error: %v", weakErr)
+	}
+	if err := <-strictDone; !errors.Is(err, ErrMetadataMissing) {
+		t.Fatalf("expected ErrMetadataMissing, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
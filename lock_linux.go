@@ -0,0 +1,38 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package checkpoint
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive advisory lock for path, blocking until it is
+// available, and returns a function that releases it. The lock is held on a
+// separate path+".lock" file so that path itself can be replaced while the
+// lock is held.
+func lockFile(path string, perm os.FileMode) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR|syscall.O_NOFOLLOW, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,68 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+)
+
+// Attribute keys used in log records, so that records from different
+// entry points can be correlated.
+const (
+	logKeyEndpoint   = "endpoint"
+	logKeyProduct    = "product"
+	logKeyVersion    = "version"
+	logKeyCapability = "capability"
+	logKeyCacheFile  = "cache_file"
+	logKeyStatus     = "status"
+	logKeyError      = "error"
+	logKeyReason     = "reason"
+)
+
+// logger returns l, or the logger configured by the CHECKPOINT_LOG
+// environment variable if l is nil. CHECKPOINT_LOG may be set to a level
+// (debug, info, warn or error) and logs to standard error. Any other
+// non-empty value logs at the debug level. Without either, nothing is
+// logged.
+func logger(l *slog.Logger) *slog.Logger {
+	if l != nil {
+		return l
+	}
+
+	v := strings.TrimSpace(os.Getenv("CHECKPOINT_LOG"))
+	if v == "" {
+		return slog.New(discardHandler{})
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(v)); err != nil {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})).
+		With("component", "checkpoint")
+}
+
+// discardHandler is a slog.Handler that drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// logRequestError logs a failed request, calling out timeouts since
+// those are expected when checkpoint is slow or unreachable.
+func logRequestError(log *slog.Logger, err error) {
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		log.Info("request timed out", logKeyError, err)
+		return
+	}
+	log.Info("request failed", logKeyError, err)
+}
@@ -0,0 +1,93 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck_logger(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	p := &CheckParams{
+		Product:       "test",
+		Version:       "1.0",
+		CacheFile:     filepath.Join(dir, "cache"),
+		SignatureFile: filepath.Join(dir, "signature"),
+		Policy:        &Policy{},
+		Logger:        log,
+		HTTPClient:    mockClient,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Check(p); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="cache miss" endpoint=check product=test version=1.0 cache_file=`,
+		`msg="created signature" endpoint=check product=test version=1.0`,
+		`msg="cache hit" endpoint=check product=test version=1.0 cache_file=`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCheck_loggerDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+
+	_, err := Check(&CheckParams{
+		Product: "test",
+		Version: "1.0",
+		Policy:  &Policy{DisableCheck: true},
+		Logger:  log,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if want := `msg="check disabled by policy" product=test version=1.0 capability=check`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("missing %q in:\n%s", want, buf.String())
+	}
+}
+
+func TestLogger_env(t *testing.T) {
+	t.Setenv("CHECKPOINT_LOG", "")
+	if logger(nil).Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("expected logging to be disabled")
+	}
+
+	t.Setenv("CHECKPOINT_LOG", "info")
+	if l := logger(nil); !l.Enabled(context.Background(), slog.LevelInfo) || l.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("expected info level")
+	}
+
+	t.Setenv("CHECKPOINT_LOG", "1")
+	if !logger(nil).Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("expected debug level")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMemoTTL and defaultMemoMaxEntries are the defaults for a Memo.
+	defaultMemoTTL        = time.Hour
+	defaultMemoMaxEntries = 128
+)
+
+// Memo is an in-process cache of Check and Versions responses. It sits in
+// front of the CacheFile, so that cached responses don't even have to be
+// read from disk again, and makes it cheap to call Check and Versions from
+// request paths.
+//
+// Responses are keyed on all of the request parameters. Only successful
+// responses are kept. A Memo is safe for concurrent use and is meant to be
+// shared through CheckParams.Memo and VersionsParams.Memo.
+type Memo struct {
+	// TTL is how long responses are kept. It defaults to one hour.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of responses kept. When it is
+	// reached, the least recently used response is evicted. It defaults
+	// to 128.
+	MaxEntries int
+
+	// Clock, if set, replaces the system clock for expiry.
+	Clock Clock
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// memoEntry is a single memoized response.
+type memoEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewMemo returns a Memo with the given TTL and maximum number of entries.
+// Zero values use the defaults.
+func NewMemo(ttl time.Duration, maxEntries int) *Memo {
+	return &Memo{TTL: ttl, MaxEntries: maxEntries}
+}
+
+// Purge removes all memoized responses. Purging a nil Memo does nothing.
+func (m *Memo) Purge() {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.entries = nil
+	m.lru = nil
+}
+
+// InvalidateCheck removes the memoized response for the given check.
+func (m *Memo) InvalidateCheck(p *CheckParams) {
+	m.remove(p.memoKey())
+}
+
+// InvalidateVersions removes the memoized response for the given versions
+// request.
+func (m *Memo) InvalidateVersions(p *VersionsParams) {
+	m.remove(p.memoKey())
+}
+
+// get returns the memoized value for key, if there is one that hasn't
+// expired. A nil Memo never has any value.
+func (m *Memo) get(key string) (interface{}, bool) {
+	if m == nil {
+		return nil, false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoEntry)
+	if !clockOrSystem(m.Clock).Now().Before(e.expires) {
+		m.lru.Remove(el)
+		delete(m.entries, key)
+		return nil, false
+	}
+
+	m.lru.MoveToFront(el)
+	return e.value, true
+}
+
+// set memoizes value for key. Setting a value on a nil Memo does nothing.
+func (m *Memo) set(key string, value interface{}) {
+	if m == nil {
+		return
+	}
+
+	ttl := m.TTL
+	if ttl <= 0 {
+		ttl = defaultMemoTTL
+	}
+	max := m.MaxEntries
+	if max <= 0 {
+		max = defaultMemoMaxEntries
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.entries == nil {
+		m.entries = make(map[string]*list.Element)
+		m.lru = list.New()
+	}
+
+	e := &memoEntry{key: key, value: value, expires: clockOrSystem(m.Clock).Now().Add(ttl)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.lru.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(e)
+
+	for m.lru.Len() > max {
+		el := m.lru.Back()
+		m.lru.Remove(el)
+		delete(m.entries, el.Value.(*memoEntry).key)
+	}
+}
+
+// remove removes the value for key. Removing from a nil Memo does nothing.
+func (m *Memo) remove(key string) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.lru.Remove(el)
+		delete(m.entries, key)
+	}
+}
+
+// memoKey joins the parts of a memo key.
+func memoKey(parts ...string) string {
+	return strings.Join(parts, "\x00")
+}
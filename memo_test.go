@@ -0,0 +1,133 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck_memo(t *testing.T) {
+	requests := 0
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test", "alerts": [{"id": 1}]}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	memo := NewMemo(0, 0)
+	params := func(version string) *CheckParams {
+		return &CheckParams{
+			Product:    "test",
+			Version:    version,
+			HTTPClient: mockClient,
+			Memo:       memo,
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := Check(params("1.0"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Product != "test" || len(resp.Alerts) != 1 {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+
+		// Changing the response must not change the memoized one.
+		resp.Alerts[0].ID = 42
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	// Different parameters are different entries.
+	if _, err := Check(params("2.0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+
+	memo.InvalidateCheck(params("1.0"))
+	resp, err := Check(params("1.0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected invalidation to cause a request, got %d", requests)
+	}
+	if resp.Alerts[0].ID != 1 {
+		t.Fatalf("unexpected alert: %#v", resp.Alerts[0])
+	}
+
+	memo.Purge()
+	if _, err := Check(params("2.0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 4 {
+		t.Fatalf("expected purge to cause a request, got %d", requests)
+	}
+}
+
+func TestMemo_maxEntries(t *testing.T) {
+	m := NewMemo(0, 2)
+	m.set("a", 1)
+	m.set("b", 2)
+	if _, ok := m.get("a"); !ok {
+		t.Fatal("expected a to be memoized")
+	}
+
+	// b is now the least recently used entry.
+	m.set("c", 3)
+	if _, ok := m.get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := m.get(key); !ok {
+			t.Fatalf("expected %s to be memoized", key)
+		}
+	}
+}
+
+func TestMemo_ttl(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	m := NewMemo(time.Minute, 0)
+	m.Clock = clock
+
+	m.set("a", 1)
+	clock.Advance(time.Minute - 1)
+	if _, ok := m.get("a"); !ok {
+		t.Fatal("expected a to be memoized")
+	}
+	clock.Advance(1)
+	if _, ok := m.get("a"); ok {
+		t.Fatal("expected a to expire")
+	}
+
+	var nilMemo *Memo
+	nilMemo.set("a", 1)
+	if _, ok := nilMemo.get("a"); ok {
+		t.Fatal("expected nil memo to be empty")
+	}
+}
+
+func TestMemo_nil(t *testing.T) {
+	var m *Memo
+	m.Purge()
+	m.InvalidateCheck(&CheckParams{Product: "test", Version: "1.0"})
+	m.InvalidateVersions(&VersionsParams{Service: "test.v1", Product: "test"})
+	m.set("key", 1)
+	if _, ok := m.get("key"); ok {
+		t.Fatal("expected a nil Memo to be empty")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMetadataMissing is returned when CheckParams.RequireMetadata is
+	// set and a response has no metadata.
+	ErrMetadataMissing = errors.New("checkpoint: response has no metadata")
+
+	// ErrMetadataExpired is returned for responses whose metadata has
+	// expired, which protects against freeze attacks.
+	ErrMetadataExpired = errors.New("checkpoint: response metadata has expired")
+
+	// ErrMetadataRollback is returned for responses whose metadata is
+	// older than metadata seen before, which protects against rollback
+	// attacks.
+	ErrMetadataRollback = errors.New("checkpoint: response metadata is older than previously seen")
+)
+
+// CheckMetadata protects a check response against rollback and freeze
+// attacks, like the timestamp role of The Update Framework. Since anyone can
+// forge it in an unsigned response, it should be used along with
+// CheckParams.PublicKeys.
+type CheckMetadata struct {
+	// Version increases with every new response from the server.
+	Version uint64 `json:"version"`
+
+	// Expires is when the response stops being valid.
+	Expires time.Time `json:"expires"`
+}
+
+// metadataFile returns the file holding the highest metadata version seen,
+// which lives next to the CacheFile. Without a CacheFile, versions aren't
+// persisted.
+func (p *CheckParams) metadataFile() string {
+	if p.CacheFile == "" {
+		return ""
+	}
+	return p.CacheFile + ".version"
+}
+
+// checkMetadata returns an error if the metadata of r has expired or is
+// older than the highest version seen so far.
+func (p *CheckParams) checkMetadata(r *CheckResponse) error {
+	m := r.Metadata
+	if m == nil {
+		if p.RequireMetadata {
+			return ErrMetadataMissing
+		}
+		return nil
+	}
+
+	if now := clockOrSystem(p.Clock).Now(); !now.Before(m.Expires) {
+		return fmt.Errorf("%w: expired at %s", ErrMetadataExpired, m.Expires.Format(time.RFC3339))
+	}
+
+	seen, err := readMetadataVersion(p.metadataFile())
+	if err != nil {
+		return err
+	}
+	if m.Version < seen {
+		return fmt.Errorf("%w: version %d, seen %d", ErrMetadataRollback, m.Version, seen)
+	}
+	return nil
+}
+
+// storeMetadata records the metadata version of r, if it is higher than
+// the highest version seen so far.
+func (p *CheckParams) storeMetadata(r *CheckResponse) error {
+	path := p.metadataFile()
+	if path == "" || r.Metadata == nil {
+		return nil
+	}
+
+	unlock, err := lockFile(path, fileMode(p.FileMode))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	seen, err := readMetadataVersion(path)
+	if err != nil {
+		return err
+	}
+	if r.Metadata.Version <= seen {
+		return nil
+	}
+
+	v := strconv.FormatUint(r.Metadata.Version, 10) + "\n"
+	return writeFileAtomic(path, []byte(v), fileMode(p.FileMode))
+}
+
+// readMetadataVersion returns the version stored at path, or zero if
+// there is none.
+func readMetadataVersion(path string) (uint64, error) {
+	if path == "" {
+		return 0, nil
+	}
+	if err := checkFile(path); err != nil {
+		return 0, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("checkpoint: bad metadata version file %s: %w", path, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,143 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer is a local checkpoint server returning signed responses with
+// the metadata version and expiry it is given.
+type fakeServer struct {
+	*httptest.Server
+	priv    ed25519.PrivateKey
+	version uint64
+	expires time.Time
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	_, priv := testKey(t, 1)
+	s := &fakeServer{priv: priv}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := []byte(fmt.Sprintf(
+			`{"product": "test", "current_version": "1.0.%d", "metadata": {"version": %d, "expires": %q}}`,
+			s.version, s.version, s.expires.Format(time.RFC3339)))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(marshalSigned(body, ed25519.Sign(s.priv, body)))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client returns a client sending all requests to the fake server.
+func (s *fakeServer) client() *http.Client {
+	u, _ := url.Parse(s.URL)
+	return &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req.URL.Scheme = u.Scheme
+			req.URL.Host = u.Host
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+}
+
+func TestCheck_metadata(t *testing.T) {
+	server := newFakeServer(t)
+	clock := NewFakeClock(time.Now())
+	cacheFile := filepath.Join(t.TempDir(), "cache")
+	params := func() *CheckParams {
+		return &CheckParams{
+			Product:              "test",
+			Version:              "1.0",
+			CacheFile:            cacheFile,
+			PublicKeys:           []ed25519.PublicKey{server.priv.Public().(ed25519.PublicKey)},
+			RequireMetadata:      true,
+			DisableDeduplication: true,
+			Policy:               &Policy{},
+			Clock:                clock,
+			HTTPClient:           server.client(),
+		}
+	}
+
+	server.version = 5
+	server.expires = clock.Now().Add(time.Hour)
+	resp, err := Check(params())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.Metadata == nil || resp.Metadata.Version != 5 || resp.CurrentVersion != "1.0.5" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if b, err := os.ReadFile(cacheFile + ".version"); err != nil || strings.TrimSpace(string(b)) != "5" {
+		t.Fatalf("expected version 5 to be stored, got %q: %v", b, err)
+	}
+
+	// Once the cached metadata expires, a new response is requested.
+	server.version = 6
+	server.expires = clock.Now().Add(2 * time.Hour)
+	clock.Advance(90 * time.Minute)
+	if resp, err = Check(params()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.Metadata.Version != 6 {
+		t.Fatalf("expected a new response, got %#v", resp.Metadata)
+	}
+
+	// Older metadata is rejected.
+	if err := os.Remove(cacheFile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	server.version = 4
+	if _, err := Check(params()); !errors.Is(err, ErrMetadataRollback) {
+		t.Fatalf("expected ErrMetadataRollback, got %v", err)
+	}
+
+	// So is expired metadata.
+	server.version = 7
+	server.expires = clock.Now().Add(-time.Minute)
+	if _, err := Check(params()); !errors.Is(err, ErrMetadataExpired) {
+		t.Fatalf("expected ErrMetadataExpired, got %v", err)
+	}
+
+	// Rejected responses aren't cached.
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no cache file, got %v", err)
+	}
+}
+
+func TestCheck_metadataMissing(t *testing.T) {
+	server := newFakeServer(t)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body := []byte(`{"product": "test"}`)
+		_, _ = w.Write(marshalSigned(body, ed25519.Sign(server.priv, body)))
+	})
+
+	p := &CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		PublicKeys:           []ed25519.PublicKey{server.priv.Public().(ed25519.PublicKey)},
+		DisableDeduplication: true,
+		Policy:               &Policy{},
+		HTTPClient:           server.client(),
+	}
+	if _, err := Check(p); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p.RequireMetadata = true
+	if _, err := Check(p); !errors.Is(err, ErrMetadataMissing) {
+		t.Fatalf("expected ErrMetadataMissing, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Endpoints used as the endpoint label of metrics.
+const (
+	EndpointCheck     = "check"
+	EndpointVersions  = "versions"
+	EndpointTelemetry = "telemetry"
+)
+
+// Reasons used as the reason label of dropped reports.
+const (
+	DropPolicy  = "policy"
+	DropConsent = "consent"
+	DropSampled = "sampled"
+)
+
+// Metrics receives measurements of the requests made by Check, Versions
+// and Report, including the background requests of CheckInterval and
+// VersionsInterval, so that their success rate and latency can be charted.
+// Implementations must be safe for concurrent use. The promcheckpoint
+// module has an implementation that is a Prometheus collector.
+type Metrics interface {
+	// IncRequests counts a finished request. Status is the HTTP status
+	// code, or "error" if no response was received.
+	IncRequests(endpoint, status string)
+
+	// ObserveLatency records how long a request took, including the
+	// requests that failed.
+	ObserveLatency(endpoint string, d time.Duration)
+
+	// IncCacheHits counts responses served from a Memo or CacheFile, and
+	// IncCacheMisses counts requests made because neither had one.
+	IncCacheHits(endpoint string)
+	IncCacheMisses(endpoint string)
+
+	// IncRetries counts requests that were sent again, such as reports
+	// sent uncompressed after the server refused the compressed body.
+	IncRetries(endpoint string)
+
+	// IncDroppedReports counts reports that weren't sent, with one of
+	// the Drop reasons.
+	IncDroppedReports(reason string)
+
+	// IncStaleServes counts expired cached responses that were served
+	// because the request failed.
+	IncStaleServes(endpoint string)
+}
+
+// NopMetrics is a Metrics that discards all measurements. It is used when
+// no Metrics is given.
+type NopMetrics struct{}
+
+func (NopMetrics) IncRequests(string, string)           {}
+func (NopMetrics) ObserveLatency(string, time.Duration) {}
+func (NopMetrics) IncCacheHits(string)                  {}
+func (NopMetrics) IncCacheMisses(string)                {}
+func (NopMetrics) IncRetries(string)                    {}
+func (NopMetrics) IncDroppedReports(string)             {}
+func (NopMetrics) IncStaleServes(string)                {}
+
+// metricsOrNop returns m, or NopMetrics if m is nil.
+func metricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return NopMetrics{}
+	}
+	return m
+}
+
+// observeRequest records a request to endpoint that started at start and
+// finished with resp or err.
+func observeRequest(m Metrics, endpoint string, start, end time.Time, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	m.IncRequests(endpoint, status)
+	m.ObserveLatency(endpoint, end.Sub(start))
+}
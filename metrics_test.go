@@ -0,0 +1,184 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics counts the measurements it receives.
+type recordingMetrics struct {
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+func (m *recordingMetrics) inc(parts ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[strings.Join(parts, " ")]++
+}
+
+func (m *recordingMetrics) IncRequests(endpoint, status string) {
+	m.inc("requests", endpoint, status)
+}
+func (m *recordingMetrics) ObserveLatency(endpoint string, d time.Duration) {
+	m.inc("latency", endpoint, d.String())
+}
+func (m *recordingMetrics) IncCacheHits(endpoint string)    { m.inc("hits", endpoint) }
+func (m *recordingMetrics) IncCacheMisses(endpoint string)  { m.inc("misses", endpoint) }
+func (m *recordingMetrics) IncRetries(endpoint string)      { m.inc("retries", endpoint) }
+func (m *recordingMetrics) IncDroppedReports(reason string) { m.inc("dropped", reason) }
+func (m *recordingMetrics) IncStaleServes(endpoint string)  { m.inc("stale", endpoint) }
+
+func TestCheck_metrics(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			clock.Advance(250 * time.Millisecond)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	metrics := &recordingMetrics{}
+	for i := 0; i < 3; i++ {
+		_, err := Check(&CheckParams{
+			Product:    "test",
+			Version:    "1.0",
+			CacheFile:  filepath.Join(t.TempDir(), "cache"),
+			Policy:     &Policy{},
+			Clock:      clock,
+			Metrics:    metrics,
+			HTTPClient: mockClient,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	expected := map[string]int{
+		"requests check 200":  3,
+		"latency check 250ms": 3,
+		"misses check":        3,
+	}
+	if !reflect.DeepEqual(metrics.counts, expected) {
+		t.Fatalf("expected %v, got %v", expected, metrics.counts)
+	}
+}
+
+func TestVersions_metrics(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	fail := false
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if fail {
+				return nil, errors.New("offline")
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"service": "test.v1"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	metrics := &recordingMetrics{}
+	p := &VersionsParams{
+		Service:    "test.v1",
+		Product:    "test",
+		CacheFile:  filepath.Join(t.TempDir(), "versions"),
+		Policy:     &Policy{},
+		Clock:      clock,
+		Metrics:    metrics,
+		HTTPClient: mockClient,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Versions(p); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	clock.Advance(72 * time.Hour)
+	fail = true
+	if _, err := Versions(p); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]int{
+		"requests versions 200":   1,
+		"requests versions error": 1,
+		"latency versions 0s":     2,
+		"hits versions":           1,
+		"misses versions":         2,
+		"stale versions":          1,
+	}
+	if !reflect.DeepEqual(metrics.counts, expected) {
+		t.Fatalf("expected %v, got %v", expected, metrics.counts)
+	}
+}
+
+func TestReport_metrics(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			status := 201
+			if req.Header.Get("Content-Encoding") != "" {
+				status = http.StatusUnsupportedMediaType
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	metrics := &recordingMetrics{}
+	err := Report(context.Background(), &ReportParams{
+		Signature:   "sig",
+		Product:     "prod",
+		Compression: &Compression{MinSize: -1},
+		Policy:      &Policy{},
+		Metrics:     metrics,
+		HTTPClient:  mockClient,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = Report(context.Background(), &ReportParams{
+		Product: "prod",
+		Policy:  &Policy{DisableTelemetry: true},
+		Metrics: metrics,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, key := range []string{
+		"requests telemetry 415",
+		"requests telemetry 201",
+		"retries telemetry",
+		"dropped policy",
+	} {
+		if metrics.counts[key] != 1 {
+			t.Errorf("expected 1 %q, got %v", key, metrics.counts)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+module github.com/hashicorp/go-checkpoint/otelcheckpoint
+
+go 1.24.0
+
+// The replace only applies when building from this repository, so that the
+// module is tested against the checkpoint package next to it. Consumers
+// ignore it and use the required release.
+replace github.com/hashicorp/go-checkpoint => ../
+
+require (
+	github.com/hashicorp/go-checkpoint v0.6.0
+	go.opentelemetry.io/otel v1.40.0
+	go.opentelemetry.io/otel/sdk v1.40.0
+	go.opentelemetry.io/otel/trace v1.40.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	github.com/hashicorp/go-uuid v1.0.3 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	go.opentelemetry.io/otel/metric v1.40.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+)
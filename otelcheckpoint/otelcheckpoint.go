@@ -0,0 +1,128 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package otelcheckpoint traces checkpoint requests with OpenTelemetry. It
+// is a separate module so that the checkpoint package doesn't depend on
+// OpenTelemetry.
+//
+// Tracer creates spans for the steps of Check, Versions and Report, and
+// Transport creates a client span for each HTTP request:
+//
+//	tracer := otelcheckpoint.NewTracer(nil)
+//	checkpoint.CheckContext(ctx, &checkpoint.CheckParams{
+//		Product:    "terraform",
+//		Version:    "1.0.0",
+//		Tracer:     tracer,
+//		HTTPClient: &http.Client{Transport: otelcheckpoint.NewTransport(nil, nil)},
+//	})
+//
+// Trace context is never propagated to the checkpoint API.
+package otelcheckpoint
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	checkpoint "github.com/hashicorp/go-checkpoint"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer.
+const instrumentationName = "github.com/hashicorp/go-checkpoint/otelcheckpoint"
+
+// Attribute keys set on the spans.
+const (
+	AttrEndpoint = attribute.Key("checkpoint.endpoint")
+	AttrProduct  = attribute.Key("checkpoint.product")
+	AttrVersion  = attribute.Key("checkpoint.version")
+)
+
+// tracer returns the tracer of tp, or of the global provider if tp is nil.
+func tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// Tracer implements checkpoint.Tracer with OpenTelemetry.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ checkpoint.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer that creates spans with tp, or with the global
+// tracer provider if tp is nil.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tracer(tp)}
+}
+
+// Start implements checkpoint.Tracer.
+func (t *Tracer) Start(ctx context.Context, s checkpoint.SpanInfo) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{AttrEndpoint.String(s.Endpoint)}
+	if s.Product != "" {
+		attrs = append(attrs, AttrProduct.String(s.Product))
+	}
+	if s.Version != "" {
+		attrs = append(attrs, AttrVersion.String(s.Version))
+	}
+
+	ctx, span := t.tracer.Start(ctx, s.Name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// Transport is an http.RoundTripper that creates a client span for each
+// request.
+type Transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// NewTransport wraps base, or http.DefaultTransport if base is nil, in a
+// Transport that creates spans with tp, or with the global tracer provider
+// if tp is nil.
+func NewTransport(base http.RoundTripper, tp trace.TracerProvider) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, tracer: tracer(tp)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The query holds the signature, so it is left out of the span.
+	u := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", u.String()),
+			attribute.String("server.address", req.URL.Hostname()),
+		))
+	defer span.End()
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
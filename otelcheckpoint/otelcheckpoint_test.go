@@ -0,0 +1,144 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package otelcheckpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	checkpoint "github.com/hashicorp/go-checkpoint"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// roundTripFunc lets us mock the HTTP responses.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (rtf roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rtf(req)
+}
+
+func TestCheckContext(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	mock := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "command")
+	dir := t.TempDir()
+	_, err := checkpoint.CheckContext(ctx, &checkpoint.CheckParams{
+		Product:       "test",
+		Version:       "1.0",
+		CacheFile:     filepath.Join(dir, "cache"),
+		SignatureFile: filepath.Join(dir, "signature"),
+		Policy:        &checkpoint.Policy{},
+		Tracer:        NewTracer(tp),
+		HTTPClient:    &http.Client{Transport: NewTransport(mock, tp)},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	parent.End()
+
+	var names []string
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		names = append(names, s.Name())
+		spans[s.Name()] = s
+	}
+	expected := []string{
+		checkpoint.SpanCacheLookup,
+		checkpoint.SpanSignatureLoad,
+		"HTTP GET",
+		checkpoint.SpanHTTPRequest,
+		checkpoint.SpanDecode,
+		"command",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected spans %v, got %v", expected, names)
+	}
+
+	// All spans belong to the trace of the command.
+	traceID := parent.SpanContext().TraceID()
+	for _, s := range rec.Ended() {
+		if s.SpanContext().TraceID() != traceID {
+			t.Fatalf("span %q isn't part of the trace", s.Name())
+		}
+	}
+	if p := spans["HTTP GET"].Parent().SpanID(); p != spans[checkpoint.SpanHTTPRequest].SpanContext().SpanID() {
+		t.Fatal("expected the HTTP span to be a child of the request span")
+	}
+
+	attrs := attributes(spans[checkpoint.SpanDecode].Attributes())
+	for k, v := range map[attribute.Key]string{
+		AttrEndpoint: checkpoint.EndpointCheck,
+		AttrProduct:  "test",
+		AttrVersion:  "1.0",
+	} {
+		if attrs[k] != v {
+			t.Fatalf("expected %s=%q, got %q", k, v, attrs[k])
+		}
+	}
+
+	attrs = attributes(spans["HTTP GET"].Attributes())
+	if attrs["url.full"] != "https://checkpoint-api.hashicorp.com/v1/check/test" {
+		t.Fatalf("unexpected url: %q", attrs["url.full"])
+	}
+	if attrs["http.response.status_code"] != "200" {
+		t.Fatalf("unexpected status: %q", attrs["http.response.status_code"])
+	}
+}
+
+func TestCheckContext_envProxy(t *testing.T) {
+	// The proxy of the environment can't be applied to the wrapped
+	// transport, so checkpoint ignores it rather than failing.
+	t.Setenv("CHECKPOINT_PROXY", "http://127.0.0.1:1")
+
+	var requests int
+	mock := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tp := sdktrace.NewTracerProvider()
+	_, err := checkpoint.CheckContext(context.Background(), &checkpoint.CheckParams{
+		Product:    "test",
+		Version:    "1.0",
+		Policy:     &checkpoint.Policy{},
+		HTTPClient: &http.Client{Transport: NewTransport(mock, tp)},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request through the transport, got %d", requests)
+	}
+}
+
+func attributes(kvs []attribute.KeyValue) map[attribute.Key]string {
+	m := make(map[attribute.Key]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = fmt.Sprint(kv.Value.AsInterface())
+	}
+	return m
+}
@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pathsDir is the directory, relative to each base directory, that holds
+// all checkpoint state.
+var pathsDir = filepath.Join("hashicorp", "checkpoint")
+
+// Paths are the standard locations of the checkpoint state of a product,
+// following the XDG Base Directory Specification.
+type Paths struct {
+	// SignatureFile and ConsentFile live in $XDG_CONFIG_HOME, which
+	// defaults to ~/.config. The consent file is always next to the
+	// signature file, see ConsentFile.
+	SignatureFile string
+	ConsentFile   string
+
+	// CacheFile is meant for CheckParams.CacheFile and lives in
+	// $XDG_CACHE_HOME, which defaults to ~/.cache.
+	CacheFile string
+
+	// SpoolDir is a directory for telemetry waiting to be sent. It lives
+	// in $XDG_STATE_HOME, which defaults to ~/.local/state.
+	SpoolDir string
+}
+
+// PathsOptions are the options for DefaultPaths.
+type PathsOptions struct {
+	// SharedSignature, if true, uses a single signature (and consent
+	// decision) for all products instead of one per product.
+	SharedSignature bool
+
+	// LegacySignatureFile is the signature file a product used before
+	// switching to DefaultPaths, such as ~/.terraform.d/checkpoint_signature.
+	// If the new signature file doesn't exist yet, the legacy file is
+	// copied there so the install keeps its signature. The legacy file is
+	// left in place for older versions of the product.
+	LegacySignatureFile string
+}
+
+// DefaultPaths returns the standard locations of the checkpoint state for
+// the given product. If opts is nil, the defaults are used.
+func DefaultPaths(product string, opts *PathsOptions) (*Paths, error) {
+	if product == "" {
+		return nil, errors.New("checkpoint: product is required")
+	}
+	if opts == nil {
+		opts = &PathsOptions{}
+	}
+
+	configDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := xdgDir("XDG_CACHE_HOME", ".cache")
+	if err != nil {
+		return nil, err
+	}
+	stateDir, err := xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+	if err != nil {
+		return nil, err
+	}
+
+	sigDir := filepath.Join(configDir, pathsDir, product)
+	if opts.SharedSignature {
+		sigDir = filepath.Join(configDir, pathsDir)
+	}
+
+	p := &Paths{
+		SignatureFile: filepath.Join(sigDir, "signature"),
+		CacheFile:     filepath.Join(cacheDir, pathsDir, product, "check"),
+		SpoolDir:      filepath.Join(stateDir, pathsDir, product, "spool"),
+	}
+	p.ConsentFile = ConsentFile(p.SignatureFile)
+
+	if opts.LegacySignatureFile != "" {
+		if err := migrateSignature(opts.LegacySignatureFile, p.SignatureFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// xdgDir returns the base directory from the given XDG environment
+// variable, or the fallback relative to the home directory. As required by
+// the specification, relative paths in the environment are ignored.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("checkpoint: can't determine %s: %w", env, err)
+	}
+	return filepath.Join(home, fallback), nil
+}
+
+// migrateSignature copies the legacy signature file to path, unless path
+// already exists or there is no legacy file.
+func migrateSignature(legacy, path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return nil
+	}
+	if err := checkFile(legacy); err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(legacy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := mkdirFor(path, DefaultDirMode); err != nil {
+		return err
+	}
+
+	// If another process migrated the file concurrently, it has the same
+	// content, so it doesn't matter who wins.
+	_, err = writeFileExclusive(path, b, DefaultFileMode)
+	return err
+}
@@ -0,0 +1,101 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+
+	p, err := DefaultPaths("test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Paths{
+		SignatureFile: filepath.Join(dir, "config", "hashicorp", "checkpoint", "test", "signature"),
+		ConsentFile:   filepath.Join(dir, "config", "hashicorp", "checkpoint", "test", consentFileName),
+		CacheFile:     filepath.Join(dir, "cache", "hashicorp", "checkpoint", "test", "check"),
+		SpoolDir:      filepath.Join(dir, "state", "hashicorp", "checkpoint", "test", "spool"),
+	}
+	if *p != *expected {
+		t.Fatalf("expected %#v, got %#v", expected, p)
+	}
+
+	shared, err := DefaultPaths("test", &PathsOptions{SharedSignature: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := filepath.Join(dir, "config", "hashicorp", "checkpoint", "signature"); shared.SignatureFile != expected {
+		t.Fatalf("expected shared signature %s, got %s", expected, shared.SignatureFile)
+	}
+}
+
+func TestDefaultPaths_home(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "relative/is/ignored")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	p, err := DefaultPaths("test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := filepath.Join(home, ".config", "hashicorp", "checkpoint", "test", "signature"); p.SignatureFile != expected {
+		t.Fatalf("expected %s, got %s", expected, p.SignatureFile)
+	}
+	if expected := filepath.Join(home, ".cache", "hashicorp", "checkpoint", "test", "check"); p.CacheFile != expected {
+		t.Fatalf("expected %s, got %s", expected, p.CacheFile)
+	}
+	if expected := filepath.Join(home, ".local", "state", "hashicorp", "checkpoint", "test", "spool"); p.SpoolDir != expected {
+		t.Fatalf("expected %s, got %s", expected, p.SpoolDir)
+	}
+}
+
+func TestDefaultPaths_migrate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	legacy := filepath.Join(dir, ".test.d", "checkpoint_signature")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(legacy, []byte("legacy-signature\n\n"+userMessage+"\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, err := DefaultPaths("test", &PathsOptions{LegacySignatureFile: legacy})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sig, err := (&FileSignatureStore{Path: p.SignatureFile}).Load(); err != nil || sig != "legacy-signature" {
+		t.Fatalf("expected migrated signature, got %q (%v)", sig, err)
+	}
+
+	// Once migrated, the legacy file is no longer used.
+	if err := os.WriteFile(legacy, []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := DefaultPaths("test", &PathsOptions{LegacySignatureFile: legacy}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sig, _ := (&FileSignatureStore{Path: p.SignatureFile}).Load(); sig != "legacy-signature" {
+		t.Fatalf("expected migrated signature to be kept, got %q", sig)
+	}
+
+	// A missing legacy file isn't an error.
+	if _, err := DefaultPaths("other", &PathsOptions{LegacySignatureFile: filepath.Join(dir, "missing")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
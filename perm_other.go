@@ -0,0 +1,13 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !unix
+
+package checkpoint
+
+import "os"
+
+// checkOwner is a no-op on platforms without Unix file ownership.
+func checkOwner(path string, fi os.FileInfo) error {
+	return nil
+}
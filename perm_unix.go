@@ -0,0 +1,31 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build unix
+
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner verifies that the file described by fi is owned by the
+// current user. Directories owned by root, such as /tmp, are trusted too.
+func checkOwner(path string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	uid := int(st.Uid)
+	if uid == os.Geteuid() || (fi.IsDir() && uid == 0) {
+		return nil
+	}
+
+	return &UnsafeFileError{
+		Path:   path,
+		Reason: fmt.Sprintf("it is owned by uid %d", uid),
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"os"
+	"strings"
+)
+
+// Capability is a part of checkpoint that can be opted out of on its own.
+type Capability int
+
+const (
+	// CapabilityCheck covers update checks and security alerts: Check,
+	// CheckInterval and Versions.
+	CapabilityCheck Capability = iota
+
+	// CapabilityTelemetry covers telemetry reports sent by Report.
+	CapabilityTelemetry
+)
+
+func (c Capability) String() string {
+	switch c {
+	case CapabilityCheck:
+		return "check"
+	case CapabilityTelemetry:
+		return "telemetry"
+	default:
+		return "unknown"
+	}
+}
+
+// Policy decides which capabilities of checkpoint may be used. Every
+// entry point of this package consults a Policy before making a request.
+// The environment is always honored on top of it: see PolicyFromEnv. The
+// environment can only disable capabilities, never enable them.
+type Policy struct {
+	// DisableAll disables every capability. It is set by
+	// CHECKPOINT_DISABLE.
+	DisableAll bool
+
+	// DisableCheck disables update checks and alerts. It is set by
+	// CHECKPOINT_DISABLE_CHECK.
+	DisableCheck bool
+
+	// DisableTelemetry disables telemetry reports. It is set by
+	// CHECKPOINT_DISABLE_TELEMETRY, or DO_NOT_TRACK set to 1 or true.
+	DisableTelemetry bool
+
+	// RequireConsent, if true, makes Report refuse to send anything until
+	// the user has granted consent for PolicyVersion. The decision is read
+	// from ConsentFile, which defaults to the consent file next to the
+	// report's SignatureFile. See RecordConsent.
+	RequireConsent bool
+	ConsentFile    string
+	PolicyVersion  string
+}
+
+// PolicyFromEnv returns the policy configured by the environment. Like
+// CHECKPOINT_DISABLE, the CHECKPOINT_DISABLE_CHECK and
+// CHECKPOINT_DISABLE_TELEMETRY variables disable their capability when set
+// to any non-empty value. DO_NOT_TRACK follows the community convention
+// (https://consoledonottrack.com) and disables telemetry when set to 1 or
+// true, in any case.
+func PolicyFromEnv() *Policy {
+	dnt := strings.TrimSpace(os.Getenv("DO_NOT_TRACK"))
+
+	return &Policy{
+		DisableAll:       os.Getenv("CHECKPOINT_DISABLE") != "",
+		DisableCheck:     os.Getenv("CHECKPOINT_DISABLE_CHECK") != "",
+		DisableTelemetry: os.Getenv("CHECKPOINT_DISABLE_TELEMETRY") != "" || dnt == "1" || strings.EqualFold(dnt, "true"),
+	}
+}
+
+// Allowed reports whether the capability may be used. If force is true,
+// the capability is always allowed: within HashiCorp products, this is
+// ONLY USED when the user specifically requests it.
+//
+// A nil Policy allows everything.
+func (p *Policy) Allowed(c Capability, force bool) bool {
+	if p == nil || force {
+		return true
+	}
+	if p.DisableAll {
+		return false
+	}
+
+	switch c {
+	case CapabilityCheck:
+		return !p.DisableCheck
+	case CapabilityTelemetry:
+		return !p.DisableTelemetry
+	default:
+		return true
+	}
+}
+
+// policyOrEnv returns the policy from the environment, with the settings
+// of p, if any, added to it. p itself isn't modified.
+func policyOrEnv(p *Policy) *Policy {
+	env := PolicyFromEnv()
+	if p == nil {
+		return env
+	}
+
+	merged := *p
+	merged.DisableAll = merged.DisableAll || env.DisableAll
+	merged.DisableCheck = merged.DisableCheck || env.DisableCheck
+	merged.DisableTelemetry = merged.DisableTelemetry || env.DisableTelemetry
+	return &merged
+}
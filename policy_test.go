@@ -0,0 +1,96 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPolicyFromEnv(t *testing.T) {
+	cases := []struct {
+		env       map[string]string
+		check     bool
+		telemetry bool
+	}{
+		{nil, true, true},
+		{map[string]string{"CHECKPOINT_DISABLE": "1"}, false, false},
+		{map[string]string{"CHECKPOINT_DISABLE_CHECK": "1"}, false, true},
+		{map[string]string{"CHECKPOINT_DISABLE_TELEMETRY": "1"}, true, false},
+		{map[string]string{"DO_NOT_TRACK": "1"}, true, false},
+		{map[string]string{"DO_NOT_TRACK": "TRUE"}, true, false},
+		{map[string]string{"DO_NOT_TRACK": "0"}, true, true},
+	}
+
+	for _, tc := range cases {
+		for _, k := range []string{"CHECKPOINT_DISABLE", "CHECKPOINT_DISABLE_CHECK", "CHECKPOINT_DISABLE_TELEMETRY", "DO_NOT_TRACK"} {
+			t.Setenv(k, tc.env[k])
+		}
+
+		p := PolicyFromEnv()
+		if actual := p.Allowed(CapabilityCheck, false); actual != tc.check {
+			t.Fatalf("%v: expected check %v, got %v", tc.env, tc.check, actual)
+		}
+		if actual := p.Allowed(CapabilityTelemetry, false); actual != tc.telemetry {
+			t.Fatalf("%v: expected telemetry %v, got %v", tc.env, tc.telemetry, actual)
+		}
+		if !p.Allowed(CapabilityCheck, true) || !p.Allowed(CapabilityTelemetry, true) {
+			t.Fatalf("%v: expected force to allow everything", tc.env)
+		}
+	}
+}
+
+func TestPolicy_telemetryOnly(t *testing.T) {
+	t.Setenv("CHECKPOINT_DISABLE_TELEMETRY", "1")
+
+	var requests []string
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.URL.Path)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	if _, err := Check(&CheckParams{Product: "test", Version: "1.0", HTTPClient: mockClient}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := Report(context.Background(), &ReportParams{Signature: "sig", Product: "test", HTTPClient: mockClient})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "/v1/check/test" {
+		t.Fatalf("expected only the check request, got %#v", requests)
+	}
+}
+
+func TestPolicy_envOverridesPolicy(t *testing.T) {
+	t.Setenv("CHECKPOINT_DISABLE", "1")
+
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		}),
+	}
+
+	policy := &Policy{RequireConsent: true}
+	resp, err := Check(&CheckParams{Product: "test", Version: "1.0", Policy: policy, HTTPClient: mockClient})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Product != "" {
+		t.Fatalf("expected an empty response, got %#v", resp)
+	}
+	if policy.DisableAll {
+		t.Fatal("expected the policy to be left untouched")
+	}
+}
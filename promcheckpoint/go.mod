@@ -0,0 +1,28 @@
+module github.com/hashicorp/go-checkpoint/promcheckpoint
+
+go 1.23.0
+
+// The replace only applies when building from this repository, so that the
+// module is tested against the checkpoint package next to it. Consumers
+// ignore it and use the required release.
+replace github.com/hashicorp/go-checkpoint => ../
+
+require (
+	github.com/hashicorp/go-checkpoint v0.6.0
+	github.com/prometheus/client_golang v1.23.2
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	github.com/hashicorp/go-uuid v1.0.3 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.66.1 // indirect
+	github.com/prometheus/procfs v0.16.1 // indirect
+	go.yaml.in/yaml/v2 v2.4.2 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	google.golang.org/protobuf v1.36.8 // indirect
+)
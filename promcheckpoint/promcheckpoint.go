@@ -0,0 +1,167 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package promcheckpoint implements checkpoint.Metrics with the Prometheus
+// client library. Metrics is a prometheus.Collector, so it can be
+// registered with the registry a product already exposes:
+//
+//	metrics := promcheckpoint.New()
+//	prometheus.MustRegister(metrics)
+//
+//	checkpoint.Check(&checkpoint.CheckParams{
+//		Product: "terraform",
+//		Version: "1.0.0",
+//		Metrics: metrics,
+//	})
+package promcheckpoint
+
+import (
+	"sync"
+	"time"
+
+	checkpoint "github.com/hashicorp/go-checkpoint"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBuckets are the default upper bounds, in seconds, of the latency
+// histogram buckets.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is a checkpoint.Metrics and a prometheus.Collector. It is safe
+// for concurrent use, and the zero value is ready to use.
+type Metrics struct {
+	// Namespace is the prefix of the metric names. It defaults to
+	// "checkpoint".
+	Namespace string
+
+	// Buckets are the sorted upper bounds, in seconds, of the latency
+	// histogram buckets. They default to DefaultBuckets.
+	//
+	// Namespace and Buckets must not be changed once the Metrics has been
+	// used or registered.
+	Buckets []float64
+
+	once           sync.Once
+	requests       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	cacheHits      *prometheus.CounterVec
+	cacheMisses    *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	droppedReports *prometheus.CounterVec
+	staleServes    *prometheus.CounterVec
+}
+
+// New returns a Metrics with the default namespace and buckets.
+func New() *Metrics {
+	return &Metrics{}
+}
+
+var (
+	_ checkpoint.Metrics   = (*Metrics)(nil)
+	_ prometheus.Collector = (*Metrics)(nil)
+)
+
+// IncRequests implements checkpoint.Metrics.
+func (m *Metrics) IncRequests(endpoint, status string) {
+	m.init()
+	m.requests.WithLabelValues(endpoint, status).Inc()
+}
+
+// ObserveLatency implements checkpoint.Metrics.
+func (m *Metrics) ObserveLatency(endpoint string, d time.Duration) {
+	m.init()
+	m.latency.WithLabelValues(endpoint).Observe(d.Seconds())
+}
+
+// IncCacheHits implements checkpoint.Metrics.
+func (m *Metrics) IncCacheHits(endpoint string) {
+	m.init()
+	m.cacheHits.WithLabelValues(endpoint).Inc()
+}
+
+// IncCacheMisses implements checkpoint.Metrics.
+func (m *Metrics) IncCacheMisses(endpoint string) {
+	m.init()
+	m.cacheMisses.WithLabelValues(endpoint).Inc()
+}
+
+// IncRetries implements checkpoint.Metrics.
+func (m *Metrics) IncRetries(endpoint string) {
+	m.init()
+	m.retries.WithLabelValues(endpoint).Inc()
+}
+
+// IncDroppedReports implements checkpoint.Metrics.
+func (m *Metrics) IncDroppedReports(reason string) {
+	m.init()
+	m.droppedReports.WithLabelValues(reason).Inc()
+}
+
+// IncStaleServes implements checkpoint.Metrics.
+func (m *Metrics) IncStaleServes(endpoint string) {
+	m.init()
+	m.staleServes.WithLabelValues(endpoint).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	m.init()
+	return []prometheus.Collector{
+		m.requests, m.latency, m.cacheHits, m.cacheMisses,
+		m.retries, m.droppedReports, m.staleServes,
+	}
+}
+
+// init creates the metrics on first use.
+func (m *Metrics) init() {
+	m.once.Do(func() {
+		ns := m.Namespace
+		if ns == "" {
+			ns = "checkpoint"
+		}
+		buckets := m.Buckets
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+
+		counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+			return prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: ns,
+				Name:      name,
+				Help:      help,
+			}, labels)
+		}
+
+		m.requests = counter("requests_total",
+			"Requests to the checkpoint API by endpoint and status.", "endpoint", "status")
+		m.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests to the checkpoint API.",
+			Buckets:   buckets,
+		}, []string{"endpoint"})
+		m.cacheHits = counter("cache_hits_total",
+			"Responses served from the memo or cache file.", "endpoint")
+		m.cacheMisses = counter("cache_misses_total",
+			"Requests made because there was no cached response.", "endpoint")
+		m.retries = counter("retries_total",
+			"Requests to the checkpoint API that were retried.", "endpoint")
+		m.droppedReports = counter("dropped_reports_total",
+			"Telemetry reports that weren't sent, by reason.", "reason")
+		m.staleServes = counter("stale_serves_total",
+			"Expired cached responses served because a request failed.", "endpoint")
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package promcheckpoint
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := &Metrics{Buckets: []float64{0.1, 1}}
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(m); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m.IncRequests("check", "200")
+	m.IncRequests("check", "200")
+	m.IncRequests("check", "error")
+	m.ObserveLatency("check", 50*time.Millisecond)
+	m.ObserveLatency("check", 500*time.Millisecond)
+	m.IncDroppedReports(`a "quoted" reason`)
+
+	expected := `# HELP checkpoint_dropped_reports_total Telemetry reports that weren't sent, by reason.
+# TYPE checkpoint_dropped_reports_total counter
+checkpoint_dropped_reports_total{reason="a \"quoted\" reason"} 1
+# HELP checkpoint_requests_total Requests to the checkpoint API by endpoint and status.
+# TYPE checkpoint_requests_total counter
+checkpoint_requests_total{endpoint="check",status="200"} 2
+checkpoint_requests_total{endpoint="check",status="error"} 1
+# HELP checkpoint_request_duration_seconds Latency of requests to the checkpoint API.
+# TYPE checkpoint_request_duration_seconds histogram
+checkpoint_request_duration_seconds_bucket{endpoint="check",le="0.1"} 1
+checkpoint_request_duration_seconds_bucket{endpoint="check",le="1"} 2
+checkpoint_request_duration_seconds_bucket{endpoint="check",le="+Inf"} 2
+checkpoint_request_duration_seconds_sum{endpoint="check"} 0.55
+checkpoint_request_duration_seconds_count{endpoint="check"} 2
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"checkpoint_dropped_reports_total", "checkpoint_requests_total", "checkpoint_request_duration_seconds")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetrics_namespace(t *testing.T) {
+	m := &Metrics{Namespace: "tf_checkpoint"}
+	m.IncCacheHits("versions")
+
+	expected := `# HELP tf_checkpoint_cache_hits_total Responses served from the memo or cache file.
+# TYPE tf_checkpoint_cache_hits_total counter
+tf_checkpoint_cache_hits_total{endpoint="versions"} 1
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "tf_checkpoint_cache_hits_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseSize is the default limit of the size of responses.
+const DefaultMaxResponseSize = 1 << 20
+
+var (
+	// ErrContentType is returned for responses that aren't JSON.
+	ErrContentType = errors.New("checkpoint: response isn't JSON")
+
+	// ErrTrailingData is returned in strict mode for responses with data
+	// after the JSON value.
+	ErrTrailingData = errors.New("checkpoint: unexpected data after response")
+)
+
+// ResponseSizeError is returned for responses larger than the limit.
+type ResponseSizeError struct {
+	Limit int64
+}
+
+func (e *ResponseSizeError) Error() string {
+	return fmt.Sprintf("checkpoint: response exceeds %d bytes", e.Limit)
+}
+
+// maxResponseSize returns limit, or the default limit if it isn't set.
+func maxResponseSize(limit int64) int64 {
+	if limit <= 0 {
+		return DefaultMaxResponseSize
+	}
+	return limit
+}
+
+// readResponse reads and decompresses the body of resp, after checking
+// that it is JSON. A missing Content-Type is accepted. The limit applies to
+// the decompressed body.
+func readResponse(resp *http.Response, limit int64, decoders map[string]Decoder) ([]byte, error) {
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+			return nil, fmt.Errorf("%w: %q", ErrContentType, ct)
+		}
+	}
+	if resp.ContentLength > maxResponseSize(limit) {
+		return nil, &ResponseSizeError{Limit: maxResponseSize(limit)}
+	}
+
+	body, closeBody, err := decodeBody(resp.Body, resp.Header.Get("Content-Encoding"), decoders)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody()
+
+	return readLimited(body, limit)
+}
+
+// readLimited reads r, returning a ResponseSizeError if it holds more than
+// limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	limit = maxResponseSize(limit)
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, &ResponseSizeError{Limit: limit}
+	}
+	return b, nil
+}
+
+// decodeJSON decodes a JSON value from r into v. In strict mode, unknown
+// fields and trailing data are errors.
+func decodeJSON(r io.Reader, v interface{}, strict bool) error {
+	dec := json.NewDecoder(r)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return ErrTrailingData
+		}
+	}
+	return nil
+}
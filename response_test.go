@@ -0,0 +1,151 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck_responseValidation(t *testing.T) {
+	large := `{"product": "test", "current_version": "` + strings.Repeat("1", 2048) + `"}`
+
+	tests := map[string]struct {
+		body        string
+		contentType string
+		limit       int64
+		strict      bool
+		check       func(error) bool
+	}{
+		"valid": {
+			body:        `{"product": "test"}`,
+			contentType: "application/json; charset=utf-8",
+		},
+		"too large": {
+			body:  large,
+			limit: 1024,
+			check: func(err error) bool {
+				var sizeErr *ResponseSizeError
+				return errors.As(err, &sizeErr) && sizeErr.Limit == 1024
+			},
+		},
+		"not json": {
+			body:        `<html></html>`,
+			contentType: "text/html",
+			check:       func(err error) bool { return errors.Is(err, ErrContentType) },
+		},
+		"unknown field": {
+			body: `{"product": "test", "unknown": true}`,
+		},
+		"unknown field strict": {
+			body:   `{"product": "test", "unknown": true}`,
+			strict: true,
+			check:  func(err error) bool { return err != nil && strings.Contains(err.Error(), "unknown field") },
+		},
+		"trailing data": {
+			body: `{"product": "test"} {}`,
+		},
+		"trailing data strict": {
+			body:   `{"product": "test"} {}`,
+			strict: true,
+			check:  func(err error) bool { return errors.Is(err, ErrTrailingData) },
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockClient := &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					h := make(http.Header)
+					if tc.contentType != "" {
+						h.Set("Content-Type", tc.contentType)
+					}
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(tc.body)),
+						Header:     h,
+					}, nil
+				}),
+			}
+
+			cacheFile := filepath.Join(t.TempDir(), "cache")
+			resp, err := Check(&CheckParams{
+				Product:              "test",
+				Version:              "1.0",
+				CacheFile:            cacheFile,
+				MaxResponseSize:      tc.limit,
+				StrictDecoding:       tc.strict,
+				DisableDeduplication: true,
+				Policy:               &Policy{},
+				HTTPClient:           mockClient,
+			})
+
+			_, statErr := os.Stat(cacheFile)
+			if tc.check == nil {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				if resp.Product != "test" || statErr != nil {
+					t.Fatalf("expected a cached response, got %#v: %v", resp, statErr)
+				}
+				return
+			}
+			if !tc.check(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !os.IsNotExist(statErr) {
+				t.Fatalf("expected no cache file, got %v", statErr)
+			}
+		})
+	}
+}
+
+func TestCheck_strictCache(t *testing.T) {
+	body := `{"product": "test", "unknown": true}`
+	requests := 0
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	p := &CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		CacheFile:            filepath.Join(t.TempDir(), "cache"),
+		DisableDeduplication: true,
+		Policy:               &Policy{},
+		HTTPClient:           mockClient,
+	}
+
+	// A cache written without strict decoding isn't used by strict checks.
+	if _, err := Check(p); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	body = `{"product": "test"}`
+	p.StrictDecoding = true
+	resp, err := Check(p)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if requests != 2 || resp.Product != "test" {
+		t.Fatalf("expected a new request, got %d: %#v", requests, resp)
+	}
+
+	// The new response replaced the cache.
+	if _, err := Check(p); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected a cache hit, got %d requests", requests)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+)
+
+// Run tracks a single execution of a product and builds the telemetry
+// report for it. It stamps StartTime and RunID when it is created and
+// EndTime when it is ended, so callers don't have to.
+//
+// A Run is safe for concurrent use.
+type Run struct {
+	// Params are the report parameters that will be sent when the run
+	// ends. Fields that aren't managed by the Run, such as SignatureFile
+	// or Sampler, can be set directly before calling End.
+	Params *ReportParams
+
+	lock     sync.Mutex
+	payload  map[string]interface{}
+	phases   []RunPhase
+	exitCode *int
+	panicVal interface{}
+	ended    bool
+}
+
+// RunPhase is a named phase of a Run and how long it took.
+type RunPhase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// StartRun starts tracking a run of the given product and version.
+func StartRun(product, version string) *Run {
+	// The RunID is only used to correlate reports, so a failure to read
+	// random bytes isn't fatal: ReportRequest will try again.
+	runID, _ := uuid.GenerateUUID()
+
+	return &Run{
+		Params: &ReportParams{
+			Product:   product,
+			Version:   version,
+			RunID:     runID,
+			StartTime: time.Now().UTC(),
+		},
+		payload: make(map[string]interface{}),
+	}
+}
+
+// Set attaches a payload field to the run. Setting the same key again
+// overwrites the previous value.
+func (r *Run) Set(key string, value interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.payload[key] = value
+}
+
+// Phase starts timing a named phase. The returned function ends the
+// phase and records its duration; it is typically deferred.
+func (r *Run) Phase(name string) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.RecordPhase(name, time.Since(start))
+		})
+	}
+}
+
+// RecordPhase records a named phase with an already measured duration.
+func (r *Run) RecordPhase(name string, d time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.phases = append(r.phases, RunPhase{Name: name, Duration: d})
+}
+
+// Exit records the exit status of the run.
+func (r *Run) Exit(code int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.exitCode = &code
+}
+
+// Recover records a panic in progress, ends the run so the report is
+// sent, and then re-panics with the same value. It does nothing if the
+// goroutine isn't panicking. It must be deferred directly:
+//
+//	run := checkpoint.StartRun("product", "1.0.0")
+//	defer run.Recover(ctx)
+func (r *Run) Recover(ctx context.Context) {
+	if v := recover(); v != nil {
+		r.RecordPanic(v)
+		_, _ = r.End(ctx)
+		panic(v)
+	}
+}
+
+// RecordPanic records v as the panic value of the run without recovering
+// from it. It is useful for callers that already recover on their own.
+func (r *Run) RecordPanic(v interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.panicVal = v
+}
+
+// Report builds the final report parameters for the run, stamping
+// EndTime if it hasn't been set yet. It doesn't send anything. The
+// returned parameters are a copy, so changing them doesn't affect the run.
+func (r *Run) Report() *ReportParams {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.report()
+}
+
+// report is Report for callers that hold the lock.
+func (r *Run) report() *ReportParams {
+	if r.Params.EndTime.IsZero() {
+		r.Params.EndTime = time.Now().UTC()
+	}
+	p := *r.Params
+
+	payload := make(map[string]interface{}, len(r.payload)+3)
+	for k, v := range r.payload {
+		payload[k] = v
+	}
+	if len(r.phases) > 0 {
+		phases := make([]RunPhase, len(r.phases))
+		copy(phases, r.phases)
+		payload["phases"] = phases
+	}
+	if r.exitCode != nil {
+		payload["exit_status"] = *r.exitCode
+	}
+	if r.panicVal != nil {
+		payload["panic"] = fmt.Sprint(r.panicVal)
+	}
+	if len(payload) > 0 {
+		p.Payload = payload
+	}
+
+	return &p
+}
+
+// End ends the run and sends its report. It returns the final report
+// parameters along with any error from sending them. Only the first call
+// to End sends a report; later calls return the final parameters without
+// sending them.
+func (r *Run) End(ctx context.Context) (*ReportParams, error) {
+	r.lock.Lock()
+	p := r.report()
+	ended := r.ended
+	r.ended = true
+	r.lock.Unlock()
+	if ended {
+		return p, nil
+	}
+
+	return p, Report(ctx, p)
+}
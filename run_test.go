@@ -0,0 +1,112 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartRun(t *testing.T) {
+	run := StartRun("test", "1.0")
+	if run.Params.RunID == "" {
+		t.Fatal("expected run ID to be generated")
+	}
+	if run.Params.StartTime.IsZero() {
+		t.Fatal("expected start time to be set")
+	}
+
+	run.Set("plugins", 3)
+	run.RecordPhase("init", time.Second)
+	end := run.Phase("plan")
+	end()
+	end()
+	run.Exit(2)
+
+	p := run.Report()
+	if p.EndTime.IsZero() || p.EndTime.Before(p.StartTime) {
+		t.Fatalf("unexpected end time: %v", p.EndTime)
+	}
+
+	payload, ok := p.Payload.(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected payload: %#v", p.Payload)
+	}
+	if payload["plugins"] != 3 {
+		t.Fatalf("expected payload field, got %#v", payload)
+	}
+	if payload["exit_status"] != 2 {
+		t.Fatalf("expected exit status, got %#v", payload)
+	}
+	phases, _ := payload["phases"].([]RunPhase)
+	if len(phases) != 2 || phases[0].Name != "init" || phases[1].Name != "plan" {
+		t.Fatalf("unexpected phases: %#v", payload["phases"])
+	}
+}
+
+func TestRun_recover(t *testing.T) {
+	if err := os.Setenv("CHECKPOINT_DISABLE", "1"); err != nil {
+		t.Fatalf("failed to set env: %v", err)
+	}
+	defer func() {
+		if err := os.Setenv("CHECKPOINT_DISABLE", ""); err != nil {
+			t.Fatalf("failed to reset env: %v", err)
+		}
+	}()
+
+	run := StartRun("test", "1.0")
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("expected panic to be re-raised, got %#v", v)
+			}
+		}()
+		defer run.Recover(context.Background())
+		panic("boom")
+	}()
+
+	p := run.Report()
+	if payload := p.Payload.(map[string]interface{}); payload["panic"] != "boom" {
+		t.Fatalf("expected panic to be recorded, got %#v", payload)
+	}
+}
+
+func TestRun_concurrentEnd(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	run := StartRun("test", "1.0")
+	run.Params.Policy = &Policy{}
+	run.Params.HTTPClient = mockClient
+
+	// Run with -race: the report is sent while the run is still in use.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			run.Set("key", i)
+			if p := run.Report(); p == run.Params {
+				t.Error("expected Report to return a copy")
+			}
+			if _, err := run.End(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
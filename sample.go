@@ -0,0 +1,169 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	mrand "math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sampler decides which telemetry reports are actually sent. It is used
+// by Report to keep high-frequency commands from flooding the telemetry
+// endpoint.
+//
+// A Sampler is safe for concurrent use and should be shared between all
+// reports of a process so that rate limits are enforced across them. The
+// zero value sends every report.
+type Sampler struct {
+	// Rate is the fraction of reports that are sent, in the range (0, 1].
+	// A Rate of zero (or anything outside of that range) sends every
+	// report. The CHECKPOINT_SAMPLE_RATE environment variable overrides
+	// this value if it is set.
+	//
+	// The rate used is recorded in ReportParams.SampleRate so that the
+	// backend can re-weight the sampled data.
+	Rate float64
+
+	// BySignature, if true, makes the sampling decision deterministic
+	// based on the report signature: a given install is either always or
+	// never sampled for a given Rate. If the report has no signature,
+	// sampling falls back to being random.
+	BySignature bool
+
+	// Limit is the maximum sustained number of reports per second sent
+	// for a single product, and Burst is the number of reports that can be
+	// sent at once before the limit kicks in. Burst defaults to Limit
+	// rounded up, with a minimum of 1. A Limit of zero disables rate
+	// limiting.
+	Limit float64
+	Burst int
+
+	// Clock and RandSource, if set, replace the system clock and the
+	// global random source, which allows tests to be deterministic.
+	Clock      Clock
+	RandSource mrand.Source
+
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+	rand    *mrand.Rand
+}
+
+// Sample reports whether r should be sent. If it returns true, the
+// sample rate used is recorded in r.SampleRate. Sampling is decided
+// first, so reports dropped by sampling don't take a rate limit token.
+//
+// A nil Sampler samples every report.
+func (s *Sampler) Sample(r *ReportParams) bool {
+	if s == nil {
+		return true
+	}
+
+	rate := s.rate()
+	if rate < 1 {
+		var n float64
+		if sig := r.Signature; s.BySignature && sig != "" {
+			sum := sha256.Sum256([]byte(sig))
+			n = float64(binary.BigEndian.Uint64(sum[:8])) / math.MaxUint64
+		} else {
+			n = s.float64()
+		}
+
+		if n >= rate {
+			return false
+		}
+	}
+
+	if !s.allow(r.Product) {
+		return false
+	}
+
+	r.SampleRate = rate
+	return true
+}
+
+// rate returns the effective sample rate, taking the environment into
+// account.
+func (s *Sampler) rate() float64 {
+	rate := s.Rate
+	if v, err := strconv.ParseFloat(os.Getenv("CHECKPOINT_SAMPLE_RATE"), 64); err == nil {
+		rate = v
+	}
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+
+	return rate
+}
+
+// allow takes a token from the bucket of the given product.
+func (s *Sampler) allow(product string) bool {
+	if s.Limit <= 0 {
+		return true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.buckets == nil {
+		s.buckets = make(map[string]*tokenBucket)
+	}
+	b, ok := s.buckets[product]
+	if !ok {
+		burst := float64(s.Burst)
+		if burst <= 0 {
+			burst = math.Max(1, math.Ceil(s.Limit))
+		}
+		b = &tokenBucket{tokens: burst, burst: burst}
+		s.buckets[product] = b
+	}
+
+	return b.take(clockOrSystem(s.Clock).Now(), s.Limit)
+}
+
+// float64 returns a random number in [0, 1).
+func (s *Sampler) float64() float64 {
+	if s.RandSource == nil {
+		return mrand.Float64()
+	}
+
+	// Sources aren't safe for concurrent use.
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.rand == nil {
+		s.rand = mrand.New(s.RandSource)
+	}
+	return s.rand.Float64()
+}
+
+// tokenBucket is a simple token bucket rate limiter. It isn't safe for
+// concurrent use on its own.
+type tokenBucket struct {
+	tokens float64
+	burst  float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time, limit float64) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * limit
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,107 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestSampler_nil(t *testing.T) {
+	var s *Sampler
+	r := &ReportParams{Product: "test"}
+	if !s.Sample(r) {
+		t.Fatal("expected nil sampler to sample")
+	}
+	if r.SampleRate != 0 {
+		t.Fatalf("expected no sample rate, got %v", r.SampleRate)
+	}
+}
+
+func TestSampler_rate(t *testing.T) {
+	s := &Sampler{Rate: 0.25}
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		r := &ReportParams{Product: "test"}
+		if s.Sample(r) {
+			sampled++
+			if r.SampleRate != 0.25 {
+				t.Fatalf("expected sample rate to be recorded, got %v", r.SampleRate)
+			}
+		}
+	}
+
+	if sampled < 2000 || sampled > 3000 {
+		t.Fatalf("unexpected number of samples: %d", sampled)
+	}
+}
+
+func TestSampler_rateEnv(t *testing.T) {
+	t.Setenv("CHECKPOINT_SAMPLE_RATE", "0.5")
+
+	s := &Sampler{Rate: 1}
+	if rate := s.rate(); rate != 0.5 {
+		t.Fatalf("expected env to override rate, got %v", rate)
+	}
+}
+
+func TestSampler_bySignature(t *testing.T) {
+	s := &Sampler{Rate: 0.5, BySignature: true}
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		sig := fmt.Sprintf("sig-%d", i)
+		first := s.Sample(&ReportParams{Product: "test", Signature: sig})
+		for j := 0; j < 5; j++ {
+			if s.Sample(&ReportParams{Product: "test", Signature: sig}) != first {
+				t.Fatalf("expected decision for %q to be stable", sig)
+			}
+		}
+		if first {
+			sampled++
+		}
+	}
+
+	if sampled < 400 || sampled > 600 {
+		t.Fatalf("unexpected number of samples: %d", sampled)
+	}
+}
+
+func TestSampler_limit(t *testing.T) {
+	s := &Sampler{Limit: 0.001, Burst: 3}
+
+	for _, product := range []string{"a", "b"} {
+		sent := 0
+		for i := 0; i < 10; i++ {
+			if s.Sample(&ReportParams{Product: product}) {
+				sent++
+			}
+		}
+		if sent != 3 {
+			t.Fatalf("expected burst of 3 for %q, got %d", product, sent)
+		}
+	}
+}
+
+func TestReport_sampleRate(t *testing.T) {
+	r := &ReportParams{Signature: "sig", Product: "prod"}
+	if !(&Sampler{Rate: 1}).Sample(r) {
+		t.Fatal("expected report to be sampled")
+	}
+
+	req, err := ReportRequest(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var actual map[string]interface{}
+	if err := json.NewDecoder(req.Body).Decode(&actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual["sample_rate"] != 1.0 {
+		t.Fatalf("expected sample_rate in payload, got %#v", actual)
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoSignature is returned by SignatureStore.Load when no signature has
+// been stored yet.
+var ErrNoSignature = errors.New("checkpoint: no signature stored")
+
+// ErrSignatureReadOnly is returned when trying to modify a signature in a
+// read-only SignatureStore.
+var ErrSignatureReadOnly = errors.New("checkpoint: signature store is read-only")
+
+// SignatureStore stores the random signature that identifies an install
+// to checkpoint. See CheckParams.Signature for what the signature is used
+// for.
+type SignatureStore interface {
+	// Load returns the stored signature, or ErrNoSignature if there is
+	// none.
+	Load() (string, error)
+
+	// Create generates and stores a new signature if there is none, and
+	// returns the stored signature.
+	Create() (string, error)
+
+	// Rotate replaces the stored signature with a newly generated one.
+	Rotate() (string, error)
+
+	// Delete removes the stored signature. Deleting a signature that
+	// doesn't exist isn't an error.
+	Delete() error
+}
+
+// LoadOrCreateSignature loads the signature from s, creating it if there
+// is none yet.
+func LoadOrCreateSignature(s SignatureStore) (string, error) {
+	sig, err := s.Load()
+	if errors.Is(err, ErrNoSignature) {
+		return s.Create()
+	}
+	return sig, err
+}
+
+// resolveSignature returns the signature to use for a request. An
+// explicit signature wins, then the store, then the CHECKPOINT_SIGNATURE
+// environment variable and finally the signature file. If none of them are
+// given, the signature is empty.
+func resolveSignature(sig string, store SignatureStore, file *FileSignatureStore, log *slog.Logger) (string, error) {
+	if sig != "" {
+		return sig, nil
+	}
+	if store == nil {
+		if os.Getenv(signatureEnv) != "" {
+			store = &EnvSignatureStore{}
+		} else if file != nil {
+			store = file
+		} else {
+			return "", nil
+		}
+	}
+
+	sig, err := store.Load()
+	if errors.Is(err, ErrNoSignature) {
+		if sig, err = store.Create(); err == nil {
+			log.Info("created signature", logKeyReason, "no existing signature")
+		}
+	}
+	if err != nil {
+		log.Debug("signature unavailable", logKeyError, err)
+	}
+	return sig, err
+}
+
+// newSignature generates a new random signature in the UUID format.
+func newSignature() (string, error) {
+	var b [16]byte
+	n := 0
+	for n < 16 {
+		n2, err := crand.Read(b[n:])
+		if err != nil {
+			return "", err
+		}
+
+		n += n2
+	}
+
+	return fmt.Sprintf(
+		"%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// FileSignatureStore stores the signature in a file. The signature is the
+// first line of the file, followed by a few "key: value" lines of metadata
+// and a message explaining what the file is to anyone who comes across it.
+// Files that only contain the signature line are read just fine.
+type FileSignatureStore struct {
+	Path string
+
+	// RotateAfter, if non-zero, makes Load rotate the signature once it is
+	// older than this. Rotating regularly keeps long-lived installs from
+	// being linkable over time, at the cost of possibly seeing repeated
+	// alerts. Acknowledged alerts survive rotation, see Acknowledge.
+	//
+	// The creation time is recorded in the file. For files written before
+	// it was, the modification time of the file is used instead.
+	RotateAfter time.Duration
+
+	// FileMode and DirMode are the permissions used when creating the
+	// signature file and its directory. They default to DefaultFileMode
+	// and DefaultDirMode.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+}
+
+// RotateSignature replaces the signature stored in the signature file at
+// path with a new one and returns it.
+func RotateSignature(path string) (string, error) {
+	return (&FileSignatureStore{Path: path}).Rotate()
+}
+
+// Load implements SignatureStore.
+func (s *FileSignatureStore) Load() (string, error) {
+	f, err := readSignatureFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if !s.expired(f) {
+		return f.signature, nil
+	}
+
+	// Another process may have rotated the signature in the meantime, so
+	// only rotate if it is still expired once we hold the lock.
+	unlock, err := lockFile(s.Path, fileMode(s.FileMode))
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	return s.loadLocked()
+}
+
+// loadLocked is Load for callers that already hold the lock of the
+// signature file. The lock isn't reentrant, so it must not call Rotate.
+func (s *FileSignatureStore) loadLocked() (string, error) {
+	f, err := readSignatureFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if s.expired(f) {
+		return s.rotateLocked()
+	}
+
+	return f.signature, nil
+}
+
+// expired reports whether the signature in f should be rotated.
+func (s *FileSignatureStore) expired(f *signatureFile) bool {
+	return s.RotateAfter > 0 && f.created.Add(s.RotateAfter).Before(time.Now())
+}
+
+// Create implements SignatureStore. Concurrent calls, even from different
+// processes, all end up with the same signature.
+func (s *FileSignatureStore) Create() (string, error) {
+	sig, err := s.Load()
+	if !errors.Is(err, ErrNoSignature) {
+		return sig, err
+	}
+
+	// Make sure the directory holding our signature exists.
+	if err := mkdirFor(s.Path, dirMode(s.DirMode)); err != nil {
+		return "", err
+	}
+
+	unlock, err := lockFile(s.Path, fileMode(s.FileMode))
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	signature, err := newSignature()
+	if err != nil {
+		return "", err
+	}
+
+	// Only create the file if nobody else did in the meantime. This also
+	// protects processes that don't share the lock with us.
+	f := &signatureFile{signature: signature, created: time.Now().UTC()}
+	created, err := writeFileExclusive(s.Path, f.bytes(), fileMode(s.FileMode))
+	if err != nil {
+		return "", err
+	}
+	if created {
+		return signature, nil
+	}
+
+	sig, err = s.loadLocked()
+	if errors.Is(err, ErrNoSignature) {
+		// The file exists but holds no signature, replace it.
+		return signature, writeFileAtomic(s.Path, f.bytes(), fileMode(s.FileMode))
+	}
+	return sig, err
+}
+
+// Rotate implements SignatureStore. Acknowledged alerts are carried over to
+// the new signature: they are the same for every install and never leave
+// this machine, so keeping them can't link the old and new signatures.
+func (s *FileSignatureStore) Rotate() (string, error) {
+	// Make sure the directory holding our signature exists.
+	if err := mkdirFor(s.Path, dirMode(s.DirMode)); err != nil {
+		return "", err
+	}
+
+	unlock, err := lockFile(s.Path, fileMode(s.FileMode))
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	return s.rotateLocked()
+}
+
+// rotateLocked is Rotate for callers that already hold the lock of the
+// signature file.
+func (s *FileSignatureStore) rotateLocked() (string, error) {
+	signature, err := newSignature()
+	if err != nil {
+		return "", err
+	}
+
+	f := &signatureFile{signature: signature, created: time.Now().UTC()}
+	if old, err := readSignatureFile(s.Path); err == nil {
+		f.acknowledged = old.acknowledged
+	}
+	if err := writeFileAtomic(s.Path, f.bytes(), fileMode(s.FileMode)); err != nil {
+		return "", err
+	}
+
+	return signature, nil
+}
+
+// Delete implements SignatureStore.
+func (s *FileSignatureStore) Delete() error {
+	if err := checkFile(s.Path); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Acknowledge records that the user has seen the alerts with the given IDs.
+// Check leaves acknowledged alerts out of its responses. The signature is
+// created if it doesn't exist yet.
+func (s *FileSignatureStore) Acknowledge(ids ...int) error {
+	if _, err := s.Create(); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(s.Path, fileMode(s.FileMode))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := readSignatureFile(s.Path)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool, len(f.acknowledged))
+	for _, id := range f.acknowledged {
+		seen[id] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			f.acknowledged = append(f.acknowledged, id)
+		}
+	}
+	sort.Ints(f.acknowledged)
+
+	return writeFileAtomic(s.Path, f.bytes(), fileMode(s.FileMode))
+}
+
+// Acknowledged returns the IDs of the alerts acknowledged with Acknowledge.
+func (s *FileSignatureStore) Acknowledged() ([]int, error) {
+	f, err := readSignatureFile(s.Path)
+	if err != nil {
+		if errors.Is(err, ErrNoSignature) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f.acknowledged, nil
+}
+
+// signatureFile is the parsed content of a signature file.
+type signatureFile struct {
+	signature    string
+	created      time.Time
+	acknowledged []int
+}
+
+// readSignatureFile reads and parses the signature file at path. It returns
+// ErrNoSignature if the file doesn't exist or has no signature.
+func readSignatureFile(path string) (*signatureFile, error) {
+	if err := checkFile(path); err != nil {
+		return nil, err
+	}
+
+	sigBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSignature
+		}
+		return nil, err
+	}
+
+	// Split the file into lines, the first one is the signature. Any
+	// metadata follows up to the first empty line.
+	lines := strings.Split(string(sigBytes), "\n")
+	f := &signatureFile{signature: strings.TrimSpace(lines[0])}
+	if f.signature == "" {
+		return nil, ErrNoSignature
+	}
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			break
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "created":
+			f.created, _ = time.Parse(time.RFC3339, value)
+		case "acknowledged":
+			for _, v := range strings.Split(value, ",") {
+				if id, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					f.acknowledged = append(f.acknowledged, id)
+				}
+			}
+		}
+	}
+
+	if f.created.IsZero() {
+		if fi, err := os.Stat(path); err == nil {
+			f.created = fi.ModTime()
+		}
+	}
+
+	return f, nil
+}
+
+// bytes returns the content of the signature file.
+func (f *signatureFile) bytes() []byte {
+	var b strings.Builder
+	b.WriteString(f.signature + "\n")
+	b.WriteString("created: " + f.created.UTC().Format(time.RFC3339) + "\n")
+	if len(f.acknowledged) > 0 {
+		ids := make([]string, len(f.acknowledged))
+		for i, id := range f.acknowledged {
+			ids[i] = strconv.Itoa(id)
+		}
+		b.WriteString("acknowledged: " + strings.Join(ids, ",") + "\n")
+	}
+	b.WriteString("\n" + userMessage + "\n")
+
+	return []byte(b.String())
+}
+
+// MemorySignatureStore keeps the signature in memory. It is mostly useful
+// for tests. The zero value is an empty store.
+type MemorySignatureStore struct {
+	lock      sync.Mutex
+	signature string
+}
+
+// Load implements SignatureStore.
+func (s *MemorySignatureStore) Load() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.signature == "" {
+		return "", ErrNoSignature
+	}
+	return s.signature, nil
+}
+
+// Create implements SignatureStore.
+func (s *MemorySignatureStore) Create() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.signature != "" {
+		return s.signature, nil
+	}
+
+	var err error
+	s.signature, err = newSignature()
+	return s.signature, err
+}
+
+// Rotate implements SignatureStore.
+func (s *MemorySignatureStore) Rotate() (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var err error
+	s.signature, err = newSignature()
+	return s.signature, err
+}
+
+// Delete implements SignatureStore.
+func (s *MemorySignatureStore) Delete() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.signature = ""
+	return nil
+}
+
+// signatureEnv is the environment variable read by EnvSignatureStore by
+// default.
+const signatureEnv = "CHECKPOINT_SIGNATURE"
+
+// EnvSignatureStore is a read-only store that takes the signature from an
+// environment variable. It is meant for ephemeral environments such as CI
+// containers, where a signature file would be thrown away after every run.
+//
+// If no store is given in the params, this store is used automatically
+// whenever CHECKPOINT_SIGNATURE is set.
+type EnvSignatureStore struct {
+	// Name is the name of the environment variable. It defaults to
+	// CHECKPOINT_SIGNATURE.
+	Name string
+}
+
+func (s *EnvSignatureStore) name() string {
+	if s.Name == "" {
+		return signatureEnv
+	}
+	return s.Name
+}
+
+// Load implements SignatureStore.
+func (s *EnvSignatureStore) Load() (string, error) {
+	sig := strings.TrimSpace(os.Getenv(s.name()))
+	if sig == "" {
+		return "", ErrNoSignature
+	}
+	return sig, nil
+}
+
+// Create implements SignatureStore. It returns the signature from the
+// environment, or ErrSignatureReadOnly if there is none.
+func (s *EnvSignatureStore) Create() (string, error) {
+	sig, err := s.Load()
+	if errors.Is(err, ErrNoSignature) {
+		return "", ErrSignatureReadOnly
+	}
+	return sig, err
+}
+
+// Rotate implements SignatureStore. It always returns ErrSignatureReadOnly.
+func (s *EnvSignatureStore) Rotate() (string, error) {
+	return "", ErrSignatureReadOnly
+}
+
+// Delete implements SignatureStore. It always returns ErrSignatureReadOnly.
+func (s *EnvSignatureStore) Delete() error {
+	return ErrSignatureReadOnly
+}
+
+// userMessage is suffixed to the signature file to provide feedback.
+var userMessage = `
+This signature is a randomly generated UUID used to de-duplicate
+alerts and version information. This signature is random, it is
+not based on any personally identifiable information. To create
+a new signature, you can simply delete this file at any time.
+See the documentation for the software using Checkpoint for more
+information on how to disable it.
+`
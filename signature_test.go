@@ -0,0 +1,221 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSignatureStore(t *testing.T, s SignatureStore) {
+	t.Helper()
+
+	if _, err := s.Load(); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("expected ErrNoSignature, got %v", err)
+	}
+
+	sig, err := s.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sig == "" {
+		t.Fatal("expected a signature")
+	}
+	if again, err := s.Create(); err != nil || again != sig {
+		t.Fatalf("expected Create to keep %q, got %q (%v)", sig, again, err)
+	}
+	if loaded, err := s.Load(); err != nil || loaded != sig {
+		t.Fatalf("expected Load to return %q, got %q (%v)", sig, loaded, err)
+	}
+
+	rotated, err := s.Rotate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated == sig {
+		t.Fatal("expected Rotate to change the signature")
+	}
+
+	if err := s.Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Delete(); err != nil {
+		t.Fatalf("unexpected error deleting twice: %v", err)
+	}
+	if _, err := s.Load(); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("expected ErrNoSignature after delete, got %v", err)
+	}
+}
+
+func TestFileSignatureStore(t *testing.T) {
+	testSignatureStore(t, &FileSignatureStore{Path: filepath.Join(t.TempDir(), "nested", "signature")})
+}
+
+func TestFileSignatureStore_format(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signature")
+	if err := os.WriteFile(path, []byte("legacy-signature\n\nsome message\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := &FileSignatureStore{Path: path}
+	if sig, err := s.Load(); err != nil || sig != "legacy-signature" {
+		t.Fatalf("expected legacy signature, got %q (%v)", sig, err)
+	}
+
+	sig, err := s.Rotate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(b), sig+"\n") || !strings.Contains(string(b), userMessage) {
+		t.Fatalf("unexpected file contents: %q", b)
+	}
+}
+
+func TestMemorySignatureStore(t *testing.T) {
+	testSignatureStore(t, &MemorySignatureStore{})
+}
+
+func TestEnvSignatureStore(t *testing.T) {
+	t.Setenv("CHECKPOINT_SIGNATURE", "")
+
+	s := &EnvSignatureStore{}
+	if _, err := s.Create(); !errors.Is(err, ErrSignatureReadOnly) {
+		t.Fatalf("expected ErrSignatureReadOnly, got %v", err)
+	}
+
+	t.Setenv("CHECKPOINT_SIGNATURE", "ci-signature")
+	if sig, err := LoadOrCreateSignature(s); err != nil || sig != "ci-signature" {
+		t.Fatalf("expected env signature, got %q (%v)", sig, err)
+	}
+	if _, err := s.Rotate(); !errors.Is(err, ErrSignatureReadOnly) {
+		t.Fatalf("expected ErrSignatureReadOnly, got %v", err)
+	}
+	if err := s.Delete(); !errors.Is(err, ErrSignatureReadOnly) {
+		t.Fatalf("expected ErrSignatureReadOnly, got %v", err)
+	}
+
+	// The environment takes precedence over the signature file.
+	path := filepath.Join(t.TempDir(), "signature")
+	if sig, err := resolveSignature("", nil, &FileSignatureStore{Path: path}, logger(nil)); err != nil || sig != "ci-signature" {
+		t.Fatalf("expected env signature, got %q (%v)", sig, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no signature file to be written, got %v", err)
+	}
+}
+
+func TestReportRequest_signatureError(t *testing.T) {
+	// A directory can't be read as a signature file.
+	_, err := ReportRequest(&ReportParams{
+		Product:       "test",
+		SignatureFile: t.TempDir(),
+	})
+	if err == nil {
+		t.Fatal("expected signature error to surface")
+	}
+}
+
+func TestFileSignatureStore_rotateAfter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signature")
+	s := &FileSignatureStore{Path: path, RotateAfter: 24 * time.Hour}
+
+	sig, err := LoadOrCreateSignature(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, err := s.Load(); err != nil || again != sig {
+		t.Fatalf("expected fresh signature to be kept, got %q (%v)", again, err)
+	}
+
+	// Legacy files have no creation time, so their age comes from the
+	// modification time.
+	if err := os.WriteFile(path, []byte("legacy\n\n"+userMessage+"\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rotated, err := s.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated == "legacy" {
+		t.Fatal("expected expired signature to be rotated")
+	}
+	if again, err := s.Load(); err != nil || again != rotated {
+		t.Fatalf("expected rotated signature to be kept, got %q (%v)", again, err)
+	}
+}
+
+func TestRotateSignature_acknowledged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signature")
+	s := &FileSignatureStore{Path: path}
+
+	if err := s.Acknowledge(3, 1, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sig, err := s.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rotated, err := RotateSignature(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated == sig {
+		t.Fatal("expected a new signature")
+	}
+
+	acked, err := s.Acknowledged()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(acked, []int{1, 3}) {
+		t.Fatalf("expected acknowledged alerts to survive, got %#v", acked)
+	}
+}
+
+func TestCheck_acknowledgedAlerts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signature")
+	if err := (&FileSignatureStore{Path: path}).Acknowledge(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"alerts": [{"id": 1}, {"id": 2}]}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	resp, err := Check(&CheckParams{
+		Product:       "test",
+		Version:       "1.0",
+		SignatureFile: path,
+		HTTPClient:    mockClient,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Alerts) != 1 || resp.Alerts[0].ID != 2 {
+		t.Fatalf("expected only the unacknowledged alert, got %#v", resp.Alerts)
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright IBM Corp. 2014, 2025
+// Copyright IBM Corp. 2014, 2026
 // SPDX-License-Identifier: MPL-2.0
 
 package checkpoint
@@ -8,13 +8,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime"
 	"time"
 
-	"github.com/hashicorp/go-cleanhttp"
 	uuid "github.com/hashicorp/go-uuid"
 )
 
@@ -30,8 +30,19 @@ type ReportParams struct {
 	// file. If the file doesn't exist, then a random signature will
 	// automatically be generated and stored here. SignatureFile will be
 	// ignored if Signature is given.
-	Signature     string `json:"signature"`
-	SignatureFile string `json:"-"`
+	//
+	// SignatureStore, if given, is used instead of SignatureFile. If
+	// neither is given and CHECKPOINT_SIGNATURE is set, the signature is
+	// read from that environment variable. See SignatureStore.
+	Signature      string         `json:"signature"`
+	SignatureFile  string         `json:"-"`
+	SignatureStore SignatureStore `json:"-"`
+
+	// FileMode and DirMode are the permissions used when creating the
+	// SignatureFile and its directory. They default to DefaultFileMode and
+	// DefaultDirMode.
+	FileMode os.FileMode `json:"-"`
+	DirMode  os.FileMode `json:"-"`
 
 	StartTime     time.Time   `json:"start_time"`
 	EndTime       time.Time   `json:"end_time"`
@@ -42,37 +53,164 @@ type ReportParams struct {
 	RunID         string      `json:"run_id"`
 	SchemaVersion string      `json:"schema_version"`
 	Version       string      `json:"version"`
+
+	// SampleRate is the rate at which this report was sampled. It is set
+	// by Sampler and lets the backend re-weight sampled reports.
+	SampleRate float64 `json:"sample_rate,omitempty"`
+
+	// Sampler, if set, decides whether Report actually sends this report.
+	// Reports that aren't sampled are dropped silently, just as when
+	// CHECKPOINT_DISABLE is set.
+	Sampler *Sampler `json:"-"`
+
+	// Compression, if set, compresses the request body. See Compression
+	// for details.
+	Compression *Compression `json:"-"`
+
+	// Force, if true, will force the report even if telemetry is
+	// disabled by the policy. Within HashiCorp products, this is ONLY
+	// USED when the user specifically requests it. This is never
+	// automatically done without the user's consent.
+	Force bool `json:"-"`
+
+	// Policy decides whether reports are allowed. The environment can
+	// disable reports even if Policy allows them. See PolicyFromEnv.
+	Policy *Policy `json:"-"`
+
+	// Proxy, if set, returns the proxy for a request, such as
+	// http.ProxyURL for a fixed proxy with credentials. Otherwise, the
+	// CHECKPOINT_PROXY environment variable is used, and then the standard
+	// HTTPS_PROXY. CHECKPOINT_PROXY is ignored if HTTPClient has a
+	// transport other than an *http.Transport. Failures of the proxy are
+	// returned as a ProxyError.
+	Proxy func(*http.Request) (*url.URL, error) `json:"-"`
+
+	// TLS configures the TLS connections, such as trusted CAs and pins.
+	// See TLSOptions.
+	TLS *TLSOptions `json:"-"`
+
+	// Tracer, if set, traces the signature load and the HTTP request of
+	// the report. See Tracer.
+	Tracer Tracer `json:"-"`
+
+	// Metrics, if set, receives measurements of the requests made, such
+	// as their status and latency. See Metrics.
+	Metrics Metrics `json:"-"`
+
+	// Logger, if set, receives structured debug and info records about the
+	// decisions made, such as cache hits and misses and failed requests.
+	// If it is nil, the CHECKPOINT_LOG environment variable decides
+	// whether anything is logged.
+	Logger *slog.Logger `json:"-"`
+
+	// UserAgent, if set, replaces the User-Agent header, which otherwise
+	// names this library, the product and the platform.
+	// UserAgentExtra is appended to the default User-Agent, such as
+	// "my-wrapper/1.2".
+	UserAgent      string `json:"-"`
+	UserAgentExtra string `json:"-"`
+
+	// HTTPClient allows injecting a custom HTTP client (for testing).
+	HTTPClient *http.Client `json:"-"`
 }
 
-func (i *ReportParams) signature() string {
-	signature := i.Signature
-	if i.Signature == "" && i.SignatureFile != "" {
-		var err error
-		signature, err = checkSignature(i.SignatureFile)
-		if err != nil {
-			return ""
-		}
+func (i *ReportParams) signature() (string, error) {
+	var file *FileSignatureStore
+	if i.SignatureFile != "" {
+		file = &FileSignatureStore{Path: i.SignatureFile, FileMode: i.FileMode, DirMode: i.DirMode}
 	}
-	return signature
+	return resolveSignature(i.Signature, i.SignatureStore, file, logger(i.Logger))
 }
 
 // Report sends telemetry information to checkpoint
 func Report(ctx context.Context, r *ReportParams) error {
-	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" {
+	log := logger(r.Logger).With(logKeyEndpoint, EndpointTelemetry,
+		logKeyProduct, r.Product, logKeyVersion, r.Version)
+	metrics := metricsOrNop(r.Metrics)
+	policy := policyOrEnv(r.Policy)
+	if !policy.Allowed(CapabilityTelemetry, r.Force) {
+		log.Info("telemetry disabled by policy", logKeyCapability, CapabilityTelemetry)
+		metrics.IncDroppedReports(DropPolicy)
+		return nil
+	}
+	if !r.Force {
+		if ok, err := policy.consent(r.SignatureFile); err != nil {
+			log.Info("telemetry needs consent", logKeyError, err)
+			metrics.IncDroppedReports(DropConsent)
+			return err
+		} else if !ok {
+			log.Info("telemetry disabled by policy", logKeyReason, "consent denied")
+			metrics.IncDroppedReports(DropConsent)
+			return nil
+		}
+	}
+
+	span := SpanInfo{Endpoint: EndpointTelemetry, Product: r.Product, Version: r.Version}
+	if r.Signature == "" {
+		span.Name = SpanSignatureLoad
+		_, end := startSpan(ctx, r.Tracer, span)
+		var err error
+		r.Signature, err = r.signature()
+		end(err)
+		if err != nil {
+			return err
+		}
+	}
+	if !r.Sampler.Sample(r) {
+		log.Debug("report not sampled")
+		metrics.IncDroppedReports(DropSampled)
 		return nil
 	}
 
+	span.Name = SpanHTTPRequest
+	ctx, end := startSpan(ctx, r.Tracer, span)
+	err := send(ctx, r, log, metrics)
+	end(err)
+	return err
+}
+
+// send sends the report, retrying once without compression if the server
+// doesn't support it.
+func send(ctx context.Context, r *ReportParams, log *slog.Logger, metrics Metrics) error {
 	req, err := ReportRequest(r)
 	if err != nil {
 		return err
 	}
 
-	client := cleanhttp.DefaultClient()
+	client, err := newHTTPClient(r.HTTPClient, transportOptions{TLS: r.TLS, Proxy: r.Proxy, Log: log})
+	if err != nil {
+		return err
+	}
+	start := time.Now()
 	resp, err := client.Do(req.WithContext(ctx))
+	observeRequest(metrics, EndpointTelemetry, start, time.Now(), resp, err)
 	if err != nil {
+		logRequestError(log, err)
 		return err
 	}
+	_ = resp.Body.Close()
+
+	// If the server doesn't understand our compression, try once more
+	// without it.
+	if resp.StatusCode == http.StatusUnsupportedMediaType && req.Header.Get("Content-Encoding") != "" {
+		req, err = reportRequest(r, nil)
+		if err != nil {
+			return err
+		}
+		log.Debug("retrying without compression", logKeyStatus, resp.StatusCode)
+		metrics.IncRetries(EndpointTelemetry)
+		start = time.Now()
+		resp, err = client.Do(req.WithContext(ctx))
+		observeRequest(metrics, EndpointTelemetry, start, time.Now(), resp, err)
+		if err != nil {
+			logRequestError(log, err)
+			return err
+		}
+		_ = resp.Body.Close()
+	}
+
 	if resp.StatusCode != 201 {
+		log.Info("request failed", logKeyStatus, resp.StatusCode)
 		return fmt.Errorf("unknown status: %d", resp.StatusCode)
 	}
 
@@ -81,6 +219,10 @@ func Report(ctx context.Context, r *ReportParams) error {
 
 // ReportRequest creates a request object for making a report
 func ReportRequest(r *ReportParams) (*http.Request, error) {
+	return reportRequest(r, r.Compression)
+}
+
+func reportRequest(r *ReportParams, c *Compression) (*http.Request, error) {
 	// Populate some fields automatically if we can
 	if r.RunID == "" {
 		uuid, err := uuid.GenerateUUID()
@@ -96,13 +238,20 @@ func ReportRequest(r *ReportParams) (*http.Request, error) {
 		r.OS = runtime.GOOS
 	}
 	if r.Signature == "" {
-		r.Signature = r.signature()
+		var err error
+		if r.Signature, err = r.signature(); err != nil {
+			return nil, err
+		}
 	}
 
 	b, err := json.Marshal(r)
 	if err != nil {
 		return nil, err
 	}
+	b, encoding, err := c.compress(b)
+	if err != nil {
+		return nil, err
+	}
 
 	u := &url.URL{
 		Scheme: "https",
@@ -115,7 +264,11 @@ func ReportRequest(r *ReportParams) (*http.Request, error) {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "HashiCorp/go-checkpoint")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent(r.Product, r.Version, r.UserAgent, r.UserAgentExtra))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
 
 	return req, nil
 }
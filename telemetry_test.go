@@ -1,10 +1,15 @@
-// Copyright IBM Corp. 2014, 2025
+// Copyright IBM Corp. 2014, 2026
 // SPDX-License-Identifier: MPL-2.0
 
 package checkpoint
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -38,3 +43,76 @@ func TestReport_sendsRequest(t *testing.T) {
 		t.Fatalf("expected %#v, got %#v", expected, actual)
 	}
 }
+
+func TestReportRequest_compression(t *testing.T) {
+	r := &ReportParams{
+		Signature:   "sig",
+		Product:     "prod",
+		Payload:     strings.Repeat("plugin ", 1000),
+		Compression: &Compression{},
+	}
+
+	req, err := ReportRequest(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enc := req.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+
+	zr, err := gzip.NewReader(req.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actual ReportParams
+	if err := json.NewDecoder(zr).Decode(&actual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Payload != r.Payload {
+		t.Fatalf("expected payload to round-trip, got %#v", actual.Payload)
+	}
+
+	// Small bodies are sent as-is.
+	r.Payload = nil
+	req, err = ReportRequest(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enc := req.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no encoding, got %q", enc)
+	}
+}
+
+func TestReport_compressionFallback(t *testing.T) {
+	var encodings []string
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			enc := req.Header.Get("Content-Encoding")
+			encodings = append(encodings, enc)
+
+			status := 201
+			if enc != "" {
+				status = http.StatusUnsupportedMediaType
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	err := Report(context.Background(), &ReportParams{
+		Signature:   "sig",
+		Product:     "prod",
+		Compression: &Compression{MinSize: -1},
+		HTTPClient:  mockClient,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(encodings, []string{"gzip", ""}) {
+		t.Fatalf("unexpected requests: %#v", encodings)
+	}
+}
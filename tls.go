@@ -0,0 +1,105 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrPinMismatch is returned when the certificate chain of the server
+// doesn't match any of TLSOptions.Pins.
+var ErrPinMismatch = errors.New("checkpoint: certificate doesn't match any pin")
+
+// TLSOptions configure the TLS connections to checkpoint.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in
+	// addition to the system roots, such as the CA of a corporate proxy.
+	// The CHECKPOINT_CA_FILE environment variable is used if it is empty,
+	// unless the HTTPClient of the params has a transport other than an
+	// *http.Transport, which it can't be applied to.
+	CAFile string
+
+	// Pins, if set, are the SHA-256 hashes of the subject public key info
+	// of certificates trusted for the server. They are base64-encoded,
+	// optionally with a "sha256/" prefix. A connection is refused unless a
+	// certificate of the verified chain matches one of them.
+	Pins []string
+
+	// MinVersion is the minimum TLS version, such as tls.VersionTLS13. It
+	// defaults to TLS 1.2.
+	MinVersion uint16
+}
+
+// Config returns the TLS configuration for the options.
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o == nil {
+		o = &TLSOptions{}
+	}
+	if o.MinVersion != 0 {
+		c.MinVersion = o.MinVersion
+	}
+
+	caFile := o.CAFile
+	if caFile == "" {
+		caFile = os.Getenv("CHECKPOINT_CA_FILE")
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint: can't read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("checkpoint: no certificates in CA file %s", caFile)
+		}
+		c.RootCAs = pool
+	}
+
+	if len(o.Pins) > 0 {
+		pins := make(map[string]bool, len(o.Pins))
+		for _, pin := range o.Pins {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("checkpoint: bad pin %q", pin)
+			}
+			pins[string(b)] = true
+		}
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if pins[string(sum[:])] {
+						return nil
+					}
+				}
+			}
+			return ErrPinMismatch
+		}
+	}
+
+	return c, nil
+}
+
+// configured reports whether the options, or the environment, change the
+// default TLS configuration.
+func (o *TLSOptions) configured() bool {
+	return o.explicit() || os.Getenv("CHECKPOINT_CA_FILE") != ""
+}
+
+// explicit reports whether the options themselves, regardless of the
+// environment, change the default TLS configuration.
+func (o *TLSOptions) explicit() bool {
+	return o != nil && (o.CAFile != "" || len(o.Pins) > 0 || o.MinVersion != 0)
+}
@@ -0,0 +1,151 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTLSServer starts a local server with a self-signed certificate for
+// the checkpoint host. It returns the address of the server, the path of a
+// CA file trusting it and the pin of its key.
+func newTLSServer(t *testing.T, maxVersion uint16) (string, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "checkpoint test"},
+		DNSNames:              []string{"checkpoint-api.hashicorp.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"product": "test"}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MaxVersion:   maxVersion,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return server.Listener.Addr().String(), caFile, "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// dialAddr dials addr, whatever address is asked for.
+func dialAddr(addr string) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+func TestCheck_tls(t *testing.T) {
+	addr, caFile, pin := newTLSServer(t, tls.VersionTLS12)
+	transport := &http.Transport{DialContext: dialAddr(addr)}
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := map[string]struct {
+		tls *TLSOptions
+		env string
+		err bool
+	}{
+		"untrusted":   {tls: nil, err: true},
+		"ca file":     {tls: &TLSOptions{CAFile: caFile}},
+		"ca file env": {env: caFile},
+		"pinned":      {tls: &TLSOptions{CAFile: caFile, Pins: []string{otherPin, pin}}},
+		"bad pin":     {tls: &TLSOptions{CAFile: caFile, Pins: []string{otherPin}}, err: true},
+		"min version": {tls: &TLSOptions{CAFile: caFile, MinVersion: tls.VersionTLS13}, err: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("CHECKPOINT_CA_FILE", tc.env)
+
+			_, err := Check(&CheckParams{
+				Product:              "test",
+				Version:              "1.0",
+				DisableDeduplication: true,
+				Policy:               &Policy{},
+				TLS:                  tc.tls,
+				HTTPClient:           &http.Client{Transport: transport},
+			})
+			if tc.err != (err != nil) {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+
+	_, err := Check(&CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		DisableDeduplication: true,
+		Policy:               &Policy{},
+		TLS:                  &TLSOptions{CAFile: caFile, Pins: []string{otherPin}},
+		HTTPClient:           &http.Client{Transport: transport},
+	})
+	if !errors.Is(err, ErrPinMismatch) {
+		t.Fatalf("expected ErrPinMismatch, got %v", err)
+	}
+}
+
+func TestTLSOptions_Config(t *testing.T) {
+	t.Setenv("CHECKPOINT_CA_FILE", "")
+
+	c, err := (*TLSOptions)(nil).Config()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.MinVersion != tls.VersionTLS12 || c.RootCAs != nil || c.VerifyConnection != nil {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	if _, err := (&TLSOptions{Pins: []string{"sha256/short"}}).Config(); err == nil {
+		t.Fatal("expected error for a bad pin")
+	}
+	if _, err := (&TLSOptions{CAFile: filepath.Join(t.TempDir(), "missing")}).Config(); err == nil {
+		t.Fatal("expected error for a missing CA file")
+	}
+}
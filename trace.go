@@ -0,0 +1,42 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import "context"
+
+// Names of the spans started by Check, Versions and Report.
+const (
+	SpanCacheLookup   = "checkpoint.cache_lookup"
+	SpanSignatureLoad = "checkpoint.signature_load"
+	SpanHTTPRequest   = "checkpoint.http_request"
+	SpanDecode        = "checkpoint.decode"
+)
+
+// SpanInfo describes a span. Endpoint is one of the Endpoint constants, and
+// Product and Version are those of the request, if it has them.
+type SpanInfo struct {
+	Name     string
+	Endpoint string
+	Product  string
+	Version  string
+}
+
+// Tracer traces the steps of Check, Versions and Report: the cache lookup,
+// the signature load, the HTTP request and the decoding of the response.
+// This package doesn't depend on any tracing library. The otelcheckpoint
+// module implements Tracer with OpenTelemetry.
+type Tracer interface {
+	// Start starts a span as a child of any span in ctx, and returns the
+	// context holding the new span and a function that ends it with the
+	// error of the step, if any.
+	Start(ctx context.Context, span SpanInfo) (context.Context, func(error))
+}
+
+// startSpan starts a span with t, which may be nil.
+func startSpan(ctx context.Context, t Tracer, span SpanInfo) (context.Context, func(error)) {
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	return t.Start(ctx, span)
+}
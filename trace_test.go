@@ -0,0 +1,98 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingTracer records the spans that were ended.
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []string
+}
+
+func (t *recordingTracer) Start(ctx context.Context, s SpanInfo) (context.Context, func(error)) {
+	return ctx, func(err error) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		name := s.Name + " " + s.Endpoint + " " + s.Product
+		if err != nil {
+			name += " error"
+		}
+		t.spans = append(t.spans, name)
+	}
+}
+
+func TestVersionsContext_tracer(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"service": "test.v1"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	tracer := &recordingTracer{}
+	_, err := VersionsContext(context.Background(), &VersionsParams{
+		Service:    "test.v1",
+		Product:    "test",
+		Policy:     &Policy{},
+		Tracer:     tracer,
+		HTTPClient: mockClient,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"checkpoint.http_request versions test",
+		"checkpoint.decode versions test",
+	}
+	if !reflect.DeepEqual(tracer.spans, expected) {
+		t.Fatalf("expected %v, got %v", expected, tracer.spans)
+	}
+}
+
+func TestCheckContext_canceled(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, req.Context().Err()
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tracer := &recordingTracer{}
+	_, err := CheckContext(ctx, &CheckParams{
+		Product:              "test",
+		Version:              "1.0",
+		Policy:               &Policy{},
+		DisableDeduplication: true,
+		Tracer:               tracer,
+		HTTPClient:           mockClient,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	expected := []string{
+		"checkpoint.signature_load check test",
+		"checkpoint.http_request check test error",
+	}
+	if !reflect.DeepEqual(tracer.spans, expected) {
+		t.Fatalf("expected %v, got %v", expected, tracer.spans)
+	}
+}
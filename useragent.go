@@ -0,0 +1,39 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Version is the version of this library. It must match the latest
+// release in the CHANGELOG.
+const Version = "0.6.0"
+
+// userAgent returns the User-Agent header for a request, of the form
+//
+//	go-checkpoint/<Version> (<product>/<version>; <os>/<arch>; <go version>) <extra>
+//
+// An override replaces it entirely.
+func userAgent(product, version, override, extra string) string {
+	if override != "" {
+		return override
+	}
+
+	var details []string
+	switch {
+	case product != "" && version != "":
+		details = append(details, product+"/"+version)
+	case product != "":
+		details = append(details, product)
+	}
+	details = append(details, runtime.GOOS+"/"+runtime.GOARCH, runtime.Version())
+
+	ua := "go-checkpoint/" + Version + " (" + strings.Join(details, "; ") + ")"
+	if extra != "" {
+		ua += " " + extra
+	}
+	return ua
+}
@@ -0,0 +1,77 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheck_userAgent(t *testing.T) {
+	var ua string
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			ua = req.Header.Get("User-Agent")
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	platform := runtime.GOOS + "/" + runtime.GOARCH + "; " + runtime.Version()
+	tests := []struct {
+		userAgent string
+		extra     string
+		expected  string
+	}{
+		{"", "", "go-checkpoint/" + Version + " (test/1.0; " + platform + ")"},
+		{"", "wrapper/2.0", "go-checkpoint/" + Version + " (test/1.0; " + platform + ") wrapper/2.0"},
+		{"custom/1.0", "ignored", "custom/1.0"},
+	}
+	for _, tc := range tests {
+		_, err := Check(&CheckParams{
+			Product:              "test",
+			Version:              "1.0",
+			UserAgent:            tc.userAgent,
+			UserAgentExtra:       tc.extra,
+			DisableDeduplication: true,
+			Policy:               &Policy{},
+			HTTPClient:           mockClient,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if ua != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, ua)
+		}
+	}
+}
+
+func TestVersion_changelog(t *testing.T) {
+	f, err := os.Open("CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if heading, ok := strings.CutPrefix(s.Text(), "## "); ok {
+			if v, _, _ := strings.Cut(heading, " "); v != Version {
+				t.Fatalf("Version is %q, but the latest CHANGELOG entry is %q", Version, heading)
+			}
+			return
+		}
+	}
+	t.Fatal("no release in the CHANGELOG")
+}
@@ -0,0 +1,104 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrResponseSignature is returned when a response can't be verified with
+// any of the public keys.
+var ErrResponseSignature = errors.New("checkpoint: response signature verification failed")
+
+// responseSignatureHeader is the header holding the base64-encoded
+// ed25519 signature over the response body.
+const responseSignatureHeader = "X-Checkpoint-Signature"
+
+// signedResponse is a response with a detached signature. It is how the
+// server can send the signature in the body, and how verified responses
+// are cached so that they are verified again when they are read back.
+//
+// The signature is over the exact bytes of Response, as sent by the
+// server in the body, or of Body. Body holds a response body signed in
+// the X-Checkpoint-Signature header: it is base64-encoded so that its
+// bytes, including any whitespace around the JSON, are kept as is.
+type signedResponse struct {
+	Response  json.RawMessage `json:"response,omitempty"`
+	Body      []byte          `json:"body,omitempty"`
+	Signature []byte          `json:"signature"`
+}
+
+// signedBody returns the response body as a signedResponse. The signature
+// is taken from the X-Checkpoint-Signature header if it is set, and the
+// body must be a signedResponse otherwise. The signature isn't verified.
+func signedBody(header http.Header, body []byte) ([]byte, error) {
+	h := header.Get(responseSignatureHeader)
+	if h == "" {
+		return body, nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(h)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad %s header: %v", ErrResponseSignature, responseSignatureHeader, err)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%w: response isn't JSON", ErrResponseSignature)
+	}
+	return json.Marshal(&signedResponse{Body: body, Signature: sig})
+}
+
+// marshalSigned encodes a signedResponse with the signature in the body.
+// Unlike json.Marshal, it keeps the response as is, so that the signature
+// still matches.
+func marshalSigned(response, sig []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"response":`)
+	b.Write(response)
+	b.WriteString(`,"signature":"`)
+	b.WriteString(base64.StdEncoding.EncodeToString(sig))
+	b.WriteString(`"}`)
+	return b.Bytes()
+}
+
+// verifyResponse reads a signedResponse from r and returns the response if
+// its signature was made by one of keys.
+func verifyResponse(r io.Reader, keys []ed25519.PublicKey) ([]byte, error) {
+	var s signedResponse
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrResponseSignature, err)
+	}
+	signed := []byte(s.Response)
+	if len(s.Body) > 0 {
+		signed = s.Body
+	}
+	if len(signed) == 0 || len(s.Signature) == 0 {
+		return nil, fmt.Errorf("%w: response isn't signed", ErrResponseSignature)
+	}
+
+	for _, key := range keys {
+		if len(key) == ed25519.PublicKeySize && ed25519.Verify(key, signed, s.Signature) {
+			return signed, nil
+		}
+	}
+	return nil, ErrResponseSignature
+}
+
+// checkResult decodes the check response in b, verifying it first if
+// PublicKeys are set.
+func (p *CheckParams) checkResult(b []byte) (*CheckResponse, error) {
+	if len(p.PublicKeys) > 0 {
+		var err error
+		if b, err = verifyResponse(bytes.NewReader(b), p.PublicKeys); err != nil {
+			return nil, err
+		}
+	}
+	return checkResult(bytes.NewReader(b), p.StrictDecoding)
+}
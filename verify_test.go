@@ -0,0 +1,151 @@
+// Copyright IBM Corp. 2014, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKey(t *testing.T, seed byte) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	return priv.Public().(ed25519.PublicKey), priv
+}
+
+func TestCheck_signedHeader(t *testing.T) {
+	pub, priv := testKey(t, 1)
+	other, _ := testKey(t, 2)
+	// The trailing newline, as written by json.Encoder, is signed too.
+	body := []byte(`{"product": "test", "current_version": "1.0.2"}` + "\n")
+
+	requests := 0
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body))
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			h := make(http.Header)
+			h.Set("X-Checkpoint-Signature", sig)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader(body)),
+				Header:     h,
+			}, nil
+		}),
+	}
+
+	cacheFile := filepath.Join(t.TempDir(), "cache")
+	params := func(keys ...ed25519.PublicKey) *CheckParams {
+		return &CheckParams{
+			Product:    "test",
+			Version:    "1.0",
+			CacheFile:  cacheFile,
+			PublicKeys: keys,
+			Policy:     &Policy{},
+			HTTPClient: mockClient,
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := Check(params(other, pub))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if resp.CurrentVersion != "1.0.2" {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the verified response to be cached, got %d requests", requests)
+	}
+
+	// A cache that was tampered with is rejected and requested again.
+	b, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tampered := bytes.Replace(body, []byte("1.0.2"), []byte("6.6.6"), 1)
+	b = bytes.Replace(b,
+		[]byte(base64.StdEncoding.EncodeToString(body)),
+		[]byte(base64.StdEncoding.EncodeToString(tampered)), 1)
+	if err := os.WriteFile(cacheFile, b, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp, err := Check(params(pub))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.CurrentVersion != "1.0.2" || requests != 2 {
+		t.Fatalf("expected a new request, got %d: %#v", requests, resp)
+	}
+
+	// A response signed by an unknown key is rejected.
+	if _, err := Check(params(other)); !errors.Is(err, ErrResponseSignature) {
+		t.Fatalf("expected ErrResponseSignature, got %v", err)
+	}
+}
+
+func TestCheck_signedEnvelope(t *testing.T) {
+	pub, priv := testKey(t, 1)
+	response := []byte(`{"product": "test", "current_version": "1.0.2"}`)
+
+	tests := map[string]struct {
+		body []byte
+		err  bool
+	}{
+		"signed": {
+			body: marshalSigned(response, ed25519.Sign(priv, response)),
+		},
+		"bad signature": {
+			body: marshalSigned(response, make([]byte, ed25519.SignatureSize)),
+			err:  true,
+		},
+		"unsigned": {
+			body: response,
+			err:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockClient := &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(bytes.NewReader(tc.body)),
+						Header:     make(http.Header),
+					}, nil
+				}),
+			}
+
+			resp, err := Check(&CheckParams{
+				Product:              "test",
+				Version:              "1.0",
+				PublicKeys:           []ed25519.PublicKey{pub},
+				DisableDeduplication: true,
+				Policy:               &Policy{},
+				HTTPClient:           mockClient,
+			})
+			if tc.err {
+				if !errors.Is(err, ErrResponseSignature) {
+					t.Fatalf("expected ErrResponseSignature, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if resp.CurrentVersion != "1.0.2" {
+				t.Fatalf("bad: %#v", resp)
+			}
+		})
+	}
+}
@@ -1,18 +1,20 @@
-// Copyright IBM Corp. 2014, 2025
+// Copyright IBM Corp. 2014, 2026
 // SPDX-License-Identifier: MPL-2.0
 
 package checkpoint
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"time"
-
-	"github.com/hashicorp/go-cleanhttp"
 )
 
 // VersionsParams are the parameters for a versions request.
@@ -28,6 +30,87 @@ type VersionsParams struct {
 	// specifically requests it. This is never automatically done without
 	// the user's consent.
 	Force bool
+
+	// MaxResponseSize is the maximum size of a response, in bytes, and
+	// StrictDecoding rejects responses with unknown fields or trailing
+	// data. See CheckParams.
+	MaxResponseSize int64
+	StrictDecoding  bool
+
+	// Decoders decompress responses with content codings other than gzip
+	// and deflate, which are always supported. They are keyed on the
+	// coding, such as "br" or "zstd", and preferred over the built-in
+	// ones. Responses are decoded whatever the transport of the
+	// HTTPClient, and cached decompressed.
+	Decoders map[string]Decoder
+
+	// Policy decides whether version requests are allowed. The
+	// environment can disable them even if Policy allows them. See
+	// PolicyFromEnv.
+	Policy *Policy
+
+	// CacheFile, if specified, will cache the result of a versions
+	// request. The duration of the cache is specified by CacheDuration,
+	// and defaults to 48 hours if not specified. If the CacheFile is newer
+	// than the CacheDuration, the request will short-circuit and use those
+	// results.
+	//
+	// If the request fails, an expired CacheFile is used as a fallback, so
+	// that callers enforcing the version constraints keep working while
+	// checkpoint can't be reached.
+	CacheFile     string
+	CacheDuration time.Duration
+
+	// FileMode and DirMode are the permissions used when creating the
+	// CacheFile and its directory. They default to DefaultFileMode and
+	// DefaultDirMode.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+
+	// Memo, if set, keeps successful responses in memory. See Memo.
+	Memo *Memo
+
+	// Clock and RandSource, if set, replace the system clock and the
+	// global random source. They are used for cache expiry and the
+	// scheduling of VersionsInterval, and allow tests to be deterministic.
+	// RandSource is only used from the VersionsInterval goroutine.
+	Clock      Clock
+	RandSource mrand.Source
+
+	// Proxy, if set, returns the proxy for a request, such as
+	// http.ProxyURL for a fixed proxy with credentials. Otherwise, the
+	// CHECKPOINT_PROXY environment variable is used, and then the standard
+	// HTTPS_PROXY. CHECKPOINT_PROXY is ignored if HTTPClient has a
+	// transport other than an *http.Transport. Failures of the proxy are
+	// returned as a ProxyError.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLS configures the TLS connections, such as trusted CAs and pins.
+	// See TLSOptions.
+	TLS *TLSOptions
+
+	// Tracer, if set, traces the steps of the request. See Tracer.
+	Tracer Tracer
+
+	// Metrics, if set, receives measurements of the requests made, such
+	// as their status and latency. See Metrics.
+	Metrics Metrics
+
+	// Logger, if set, receives structured debug and info records about the
+	// decisions made, such as cache hits and misses and failed requests.
+	// If it is nil, the CHECKPOINT_LOG environment variable decides
+	// whether anything is logged.
+	Logger *slog.Logger
+
+	// UserAgent, if set, replaces the User-Agent header, which otherwise
+	// names this library, the product and the platform.
+	// UserAgentExtra is appended to the default User-Agent, such as
+	// "my-wrapper/1.2".
+	UserAgent      string
+	UserAgentExtra string
+
+	// HTTPClient allows injecting a custom HTTP client (for testing).
+	HTTPClient *http.Client
 }
 
 // VersionsResponse is the response for a versions request.
@@ -41,10 +124,129 @@ type VersionsResponse struct {
 
 // Versions returns the version constrains for a given service and product.
 func Versions(p *VersionsParams) (*VersionsResponse, error) {
-	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" && !p.Force {
+	return VersionsContext(context.Background(), p)
+}
+
+// VersionsContext is like Versions, but the request is made with ctx, which
+// also carries the parent of the spans started by VersionsParams.Tracer.
+func VersionsContext(ctx context.Context, p *VersionsParams) (*VersionsResponse, error) {
+	log := logger(p.Logger).With(logKeyEndpoint, EndpointVersions, logKeyProduct, p.Product)
+	metrics := metricsOrNop(p.Metrics)
+	if !policyOrEnv(p.Policy).Allowed(CapabilityCheck, p.Force) {
+		log.Info("versions disabled by policy", logKeyCapability, CapabilityCheck)
 		return &VersionsResponse{}, nil
 	}
 
+	memoKey := p.memoKey()
+	if v, ok := p.Memo.get(memoKey); ok {
+		log.Debug("memo hit")
+		metrics.IncCacheHits(EndpointVersions)
+		return v.(*VersionsResponse).clone(), nil
+	}
+
+	cache := &cacheFile{
+		Path:     p.CacheFile,
+		Key:      p.Service + "\x00" + p.Product,
+		Duration: p.CacheDuration,
+		Clock:    p.Clock,
+		FileMode: p.FileMode,
+		DirMode:  p.DirMode,
+	}
+
+	// If we have a cached result, then use that
+	span := SpanInfo{Endpoint: EndpointVersions, Product: p.Product}
+	var result *VersionsResponse
+	var err error
+	if p.CacheFile != "" {
+		span.Name = SpanCacheLookup
+		_, end := startSpan(ctx, p.Tracer, span)
+		result, err = versionsCache(p, cache, false, log)
+		end(err)
+	}
+	if err != nil {
+		log.Debug("cache unreadable", logKeyCacheFile, p.CacheFile, logKeyError, err)
+		return nil, err
+	}
+	if result != nil {
+		log.Debug("cache hit", logKeyCacheFile, p.CacheFile)
+		metrics.IncCacheHits(EndpointVersions)
+	} else {
+		if p.CacheFile != "" {
+			log.Debug("cache miss", logKeyCacheFile, p.CacheFile)
+		}
+		if p.CacheFile != "" || p.Memo != nil {
+			metrics.IncCacheMisses(EndpointVersions)
+		}
+		result, err = versions(ctx, p, cache, span, log, metrics)
+		if err != nil {
+			// Fall back to an expired cache if we have one.
+			if stale, _ := versionsCache(p, cache, true, log); stale != nil {
+				log.Info("using stale cache", logKeyCacheFile, p.CacheFile, logKeyError, err)
+				metrics.IncStaleServes(EndpointVersions)
+				return stale, nil
+			}
+			return nil, err
+		}
+	}
+	p.Memo.set(memoKey, result.clone())
+
+	return result, nil
+}
+
+// VersionsInterval is used to request version constraints on a given
+// interval duration, refreshing the CacheFile if there is one. The interval
+// is not exact, and requests are randomized to prevent a thundering herd.
+// However, it is expected that on average one request is performed per
+// interval. The returned channel may be closed to stop background requests.
+func VersionsInterval(p *VersionsParams, interval time.Duration, cb func(*VersionsResponse, error)) chan struct{} {
+	doneCh := make(chan struct{})
+
+	if !policyOrEnv(p.Policy).Allowed(CapabilityCheck, p.Force) {
+		return doneCh
+	}
+
+	clock := clockOrSystem(p.Clock)
+	go func() {
+		for {
+			select {
+			case <-clock.After(randomStagger(p.RandSource, interval)):
+				resp, err := Versions(p)
+				cb(resp, err)
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	return doneCh
+}
+
+// versionsCache returns the cached response, or nil if there is none or
+// it can't be decoded.
+func versionsCache(p *VersionsParams, cache *cacheFile, stale bool, log *slog.Logger) (*VersionsResponse, error) {
+	r, err := cache.open(stale)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	b, err := readLimited(r, p.MaxResponseSize)
+	var result *VersionsResponse
+	if err == nil {
+		result, err = versionsResult(bytes.NewReader(b), p.StrictDecoding)
+	}
+	if err != nil {
+		// The cache can't be decoded, such as when it was written without
+		// StrictDecoding, so it isn't used.
+		log.Info("cached response rejected", logKeyCacheFile, p.CacheFile, logKeyError, err)
+		return nil, nil
+	}
+	return result, nil
+}
+
+func versions(ctx context.Context, p *VersionsParams, cache *cacheFile, span SpanInfo, log *slog.Logger, metrics Metrics) (*VersionsResponse, error) {
 	// Set a default timeout of 1 sec for the versions request (in milliseconds)
 	timeout := 1000
 	if _, err := strconv.Atoi(os.Getenv("CHECKPOINT_TIMEOUT")); err == nil {
@@ -61,21 +263,38 @@ func Versions(p *VersionsParams) (*VersionsResponse, error) {
 		RawQuery: v.Encode(),
 	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	span.Name = SpanHTTPRequest
+	reqCtx, end := startSpan(ctx, p.Tracer, span)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", u.String(), nil)
 	if err != nil {
+		end(err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "HashiCorp/go-checkpoint")
+	// Setting Accept-Encoding turns off the transparent gzip of the
+	// transport, so responses are always decoded by readResponse.
+	req.Header.Set("Accept-Encoding", acceptEncoding(p.Decoders))
+	req.Header.Set("User-Agent", userAgent(p.Product, "", p.UserAgent, p.UserAgentExtra))
 
-	client := cleanhttp.DefaultClient()
+	// The client is a copy, so that setting the timeout doesn't race with
+	// other users of it.
+	client, err := newHTTPClient(p.HTTPClient, transportOptions{TLS: p.TLS, Proxy: p.Proxy, Log: log})
+	if err != nil {
+		end(err)
+		return nil, err
+	}
 
 	// We use a short timeout since checking for new versions is not critical
 	// enough to block on if checkpoint is broken/slow.
 	client.Timeout = time.Duration(timeout) * time.Millisecond
 
+	clock := clockOrSystem(p.Clock)
+	start := clock.Now()
 	resp, err := client.Do(req)
+	observeRequest(metrics, EndpointVersions, start, clock.Now(), resp, err)
 	if err != nil {
+		end(err)
+		logRequestError(log, err)
 		return nil, err
 	}
 	defer func() {
@@ -83,13 +302,48 @@ func Versions(p *VersionsParams) (*VersionsResponse, error) {
 	}()
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("unknown status: %d", resp.StatusCode)
+		err := fmt.Errorf("unknown status: %d", resp.StatusCode)
+		end(err)
+		log.Info("request failed", logKeyStatus, resp.StatusCode)
+		return nil, err
 	}
+	end(nil)
 
-	result := &VersionsResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+	span.Name = SpanDecode
+	_, end = startSpan(ctx, p.Tracer, span)
+	// Nothing is cached unless the response is valid.
+	b, err := readResponse(resp, p.MaxResponseSize, p.Decoders)
+	var result *VersionsResponse
+	if err == nil {
+		result, err = versionsResult(bytes.NewReader(b), p.StrictDecoding)
+	}
+	if err == nil {
+		err = cache.store(b)
+	}
+	end(err)
+	if err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
+
+func versionsResult(r io.Reader, strict bool) (*VersionsResponse, error) {
+	result := &VersionsResponse{}
+	if err := decodeJSON(r, result, strict); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// memoKey returns the key of the request in a Memo.
+func (p *VersionsParams) memoKey() string {
+	return memoKey("versions", p.Service, p.Product)
+}
+
+// clone returns a deep copy of the response.
+func (r *VersionsResponse) clone() *VersionsResponse {
+	c := *r
+	c.Excluding = append([]string(nil), r.Excluding...)
+	return &c
+}
@@ -1,13 +1,19 @@
-// Copyright IBM Corp. 2014, 2025
+// Copyright IBM Corp. 2014, 2026
 // SPDX-License-Identifier: MPL-2.0
 
 package checkpoint
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestVersions(t *testing.T) {
@@ -84,3 +90,110 @@ func TestVersions_disabled(t *testing.T) {
 		t.Fatalf("expected %#v, got: %#v", expected, actual)
 	}
 }
+
+func TestVersions_cache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "nested", "versions")
+
+	requests := 0
+	fail := false
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			if fail {
+				return nil, errors.New("offline")
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(fmt.Sprintf(
+					`{"service": %q, "product": %q, "minimum": "1.0"}`,
+					strings.TrimPrefix(req.URL.Path, "/v1/versions/"), req.URL.Query().Get("product")))),
+				Header: make(http.Header),
+			}, nil
+		}),
+	}
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	params := func(service, product string) *VersionsParams {
+		return &VersionsParams{
+			Service:    service,
+			Product:    product,
+			CacheFile:  cacheFile,
+			HTTPClient: mockClient,
+			Clock:      clock,
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		actual, err := Versions(params("test.v1", "test"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual.Service != "test.v1" || actual.Product != "test" || actual.Minimum != "1.0" {
+			t.Fatalf("unexpected response: %#v", actual)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	// The cache is keyed on the service and product.
+	actual, err := Versions(params("test.v1", "other"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 || actual.Product != "other" {
+		t.Fatalf("expected a new request for another product, got %d: %#v", requests, actual)
+	}
+
+	// An expired cache is used when the service can't be reached.
+	clock.Advance(72 * time.Hour)
+	fail = true
+	actual, err = Versions(params("test.v1", "other"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 3 || actual.Product != "other" {
+		t.Fatalf("expected stale response after a failed request, got %d: %#v", requests, actual)
+	}
+
+	// Without a matching cache, the error is returned.
+	if _, err := Versions(params("test.v1", "test")); err == nil {
+		t.Fatal("expected error without a matching cache")
+	}
+}
+
+func TestVersionsInterval(t *testing.T) {
+	mockClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`{"service": "test.v1", "product": "test"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	calledCh := make(chan *VersionsResponse, 1)
+	doneCh := VersionsInterval(&VersionsParams{
+		Service:    "test.v1",
+		Product:    "test",
+		HTTPClient: mockClient,
+	}, 10*time.Millisecond, func(resp *VersionsResponse, err error) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		select {
+		case calledCh <- resp:
+		default:
+		}
+	})
+	defer close(doneCh)
+
+	select {
+	case resp := <-calledCh:
+		if resp == nil || resp.Service != "test.v1" {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("timeout")
+	}
+}